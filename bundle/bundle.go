@@ -0,0 +1,143 @@
+package bundle
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mdsauce/nethelp/redact"
+)
+
+// File is one entry of a support bundle
+type File struct {
+	Name string
+	Data []byte
+}
+
+// Write zips every file into path.  Text is run through the redaction
+// layer once more so nothing secret ends up in a ticket attachment.
+func Write(path string, files []File) error {
+	fp, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	zw := zip.NewWriter(fp)
+	for _, f := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.Name, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write([]byte(redact.String(string(f.Data)))); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// ProxyEnv lists every proxy related environment variable, passwords masked
+func ProxyEnv() []byte {
+	var buf bytes.Buffer
+	var names []string
+	for _, kv := range os.Environ() {
+		name := strings.SplitN(kv, "=", 2)[0]
+		if strings.Contains(strings.ToLower(name), "proxy") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		buf.WriteString("No proxy environment variables are set.\n")
+	}
+	for _, name := range names {
+		fmt.Fprintf(&buf, "%s=%s\n", name, redact.String(os.Getenv(name)))
+	}
+	return buf.Bytes()
+}
+
+// NameResolution collects /etc/resolv.conf and every /etc/hosts line that
+// mentions a Sauce Labs domain
+func NameResolution() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("# /etc/resolv.conf\n")
+	if resolv, err := ioutil.ReadFile("/etc/resolv.conf"); err != nil {
+		fmt.Fprintf(&buf, "not readable: %v\n", err)
+	} else {
+		buf.Write(resolv)
+	}
+
+	buf.WriteString("\n# /etc/hosts entries for Sauce Labs domains\n")
+	hosts, err := os.Open(hostsFile())
+	if err != nil {
+		fmt.Fprintf(&buf, "not readable: %v\n", err)
+		return buf.Bytes()
+	}
+	defer hosts.Close()
+	found := false
+	scanner := bufio.NewScanner(hosts)
+	for scanner.Scan() {
+		line := scanner.Text()
+		lower := strings.ToLower(line)
+		if strings.Contains(lower, "saucelabs") || strings.Contains(lower, "testobject") {
+			buf.WriteString(line + "\n")
+			found = true
+		}
+	}
+	if !found {
+		buf.WriteString("none\n")
+	}
+	return buf.Bytes()
+}
+
+func hostsFile() string {
+	if runtime.GOOS == "windows" {
+		return os.Getenv("SystemRoot") + `\System32\drivers\etc\hosts`
+	}
+	return "/etc/hosts"
+}
+
+// Routes prints the routing table with the tool the OS ships
+func Routes() []byte {
+	switch runtime.GOOS {
+	case "windows":
+		return run("route", "print")
+	case "linux":
+		if _, err := exec.LookPath("ip"); err == nil {
+			return append(run("ip", "route", "show"), run("ip", "-6", "route", "show")...)
+		}
+	}
+	return run("netstat", "-rn")
+}
+
+// SystemInfo describes the OS and the Go runtime nethelp was built with
+func SystemInfo() []byte {
+	var buf bytes.Buffer
+	hostname, _ := os.Hostname()
+	fmt.Fprintf(&buf, "os: %s\narch: %s\ngo: %s\ncpus: %d\nhostname: %s\ntime: %s\n\n",
+		runtime.GOOS, runtime.GOARCH, runtime.Version(), runtime.NumCPU(), hostname, time.Now().Format(time.RFC3339))
+	if runtime.GOOS == "windows" {
+		buf.Write(run("cmd", "/c", "ver"))
+	} else {
+		buf.Write(run("uname", "-a"))
+	}
+	return buf.Bytes()
+}
+
+// run captures the output of a command, or why it could not run
+func run(name string, args ...string) []byte {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	header := fmt.Sprintf("$ %s %s\n", name, strings.Join(args, " "))
+	if err != nil {
+		return []byte(fmt.Sprintf("%s%s\nfailed: %v\n", header, out, err))
+	}
+	return append([]byte(header), out...)
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/mdsauce/nethelp/config"
+	"github.com/mdsauce/nethelp/snapshot"
+	"github.com/mdsauce/nethelp/suite"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// baselineCmd represents the baseline command
+var baselineCmd = &cobra.Command{
+	Use:   "baseline",
+	Short: "Save the results of a run to compare later runs against.",
+	Long: `Baselines answer "what changed since it last worked?".  Save one while
+everything works, then run 'nethelp diff <baseline>' when it does not.`,
+}
+
+// baselineSaveCmd represents the baseline save command
+var baselineSaveCmd = &cobra.Command{
+	Use:   "save [name|file.json]",
+	Short: "Run the checks and save the structured results as a baseline.",
+	Long: `Runs the selected checks and saves the results, the proxy settings and
+the DNS answers of every host.  A bare name is stored in the baselines
+directory of the nethelp config dir, "default" when omitted.  A name
+ending in .json or containing a path separator is used as a file path.
+
+$ nethelp baseline save
+$ nethelp baseline save before-firewall-change --tcp
+$ nethelp baseline save ./ci-host.json`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := "default"
+		if len(args) == 1 {
+			name = args[0]
+		}
+		path, err := baselinePath(name)
+		if err != nil {
+			log.Fatal("Could not find the baselines directory. ", err)
+		}
+		snap := currentSnapshot(cmd)
+		if err := snapshot.Save(path, snap); err != nil {
+			log.Fatal("Could not save the baseline. ", err)
+		}
+		fmt.Printf("\nBaseline of %d checks saved to %s\n", len(snap.Results), path)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(baselineCmd)
+	baselineCmd.AddCommand(baselineSaveCmd)
+	addSelectionFlags(baselineSaveCmd.Flags())
+}
+
+// baselinePath turns a baseline name into the file it is stored in
+func baselinePath(name string) (string, error) {
+	if strings.HasSuffix(name, ".json") || strings.ContainsRune(name, filepath.Separator) || strings.ContainsRune(name, '/') {
+		return name, nil
+	}
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "baselines", name+".json"), nil
+}
+
+// currentSnapshot runs the checks selected by the flags and snapshots the results
+func currentSnapshot(cmd *cobra.Command) snapshot.Snapshot {
+	setupVerbose(cmd)
+	var opts suite.Options
+	selectionFromFlags(cmd, &opts)
+	networkFromFlags(cmd, &opts)
+	_, snap := runAndRecord(cmd, opts, true)
+	return snap
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mdsauce/nethelp/bundle"
+	"github.com/mdsauce/nethelp/redact"
+	"github.com/mdsauce/nethelp/suite"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// bundleCmd represents the bundle command
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Run every check and zip the results with system details for a support ticket.",
+	Long: `Runs the full suite of HTTP, REST API and TCP checks against every data
+center and writes a single zip file containing:
+  report.html       the results as a page to forward to your network team
+  results.json      structured results of every check
+  nethelp.log       the verbose log of the run
+  proxy-env.txt     proxy environment variables, passwords masked
+  resolution.txt    /etc/resolv.conf and /etc/hosts entries for Sauce Labs domains
+  routes.txt        the routing table
+  system.txt        OS and runtime information
+  nethelp.txt       nethelp version and the flags it was run with
+
+Secrets are masked throughout.  Attach the zip to your Sauce Labs support ticket.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		output, err := cmd.Flags().GetString("output")
+		if err != nil {
+			log.Fatal("Could not get the output flag. ", err)
+		}
+		if output == "" {
+			output = fmt.Sprintf("nethelp-bundle-%s.zip", time.Now().Format("20060102-150405"))
+		}
+		opts := suite.Full()
+		if opts.SessionTest, err = cmd.Flags().GetBool("session-test"); err != nil {
+			log.Fatal("Could not get the session-test flag. ", err)
+		}
+
+		// Keep a verbose log for the bundle, and show it too with --verbose
+		var verboseLog bytes.Buffer
+		log.SetLevel(log.TraceLevel)
+		log.SetFormatter(&redact.Formatter{Formatter: &log.TextFormatter{DisableColors: true}})
+		log.SetOutput(&verboseLog)
+		if enableVerbose, _ := cmd.Flags().GetBool("verbose"); enableVerbose {
+			log.SetOutput(io.MultiWriter(os.Stdout, &verboseLog))
+		}
+
+		networkFromFlags(cmd, &opts)
+		rep, _ := runAndRecord(cmd, opts, false)
+
+		results, err := json.MarshalIndent(rep.Results, "", "  ")
+		if err != nil {
+			log.Fatal("Could not encode the results. ", err)
+		}
+		var html bytes.Buffer
+		if err := htmlReport(&html, rep); err != nil {
+			log.Warn("Could not render the HTML report. ", err)
+		}
+		files := []bundle.File{
+			{Name: "report.html", Data: html.Bytes()},
+			{Name: "results.json", Data: results},
+			{Name: "nethelp.log", Data: verboseLog.Bytes()},
+			{Name: "proxy-env.txt", Data: bundle.ProxyEnv()},
+			{Name: "resolution.txt", Data: bundle.NameResolution()},
+			{Name: "routes.txt", Data: bundle.Routes()},
+			{Name: "system.txt", Data: bundle.SystemInfo()},
+			{Name: "nethelp.txt", Data: invocation(cmd)},
+		}
+		if err := bundle.Write(output, files); err != nil {
+			log.SetOutput(os.Stderr)
+			log.Fatal("Could not write the support bundle. ", err)
+		}
+		fmt.Printf("\nSupport bundle written to %s.  Attach it to your Sauce Labs support ticket.\n", output)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(bundleCmd)
+
+	bundleCmd.Flags().StringP("output", "o", "", "path of the zip file to write. (default nethelp-bundle-<timestamp>.zip)")
+	bundleCmd.Flags().Bool("session-test", false, "also start a real WebDriver session on every data center.  Uses your Sauce Labs minutes.")
+}
+
+// invocation records the nethelp version, the command line and every flag that was set
+func invocation(cmd *cobra.Command) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "version: %s\ncommand: %s\n\nflags:\n", Version, strings.Join(os.Args, " "))
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		fmt.Fprintf(&buf, "  --%s=%s\n", f.Name, f.Value)
+	})
+	return buf.Bytes()
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mdsauce/nethelp/redact"
+	"github.com/mdsauce/nethelp/snapshot"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff <baseline> [current|file]",
+	Short: "Show what changed between a baseline and now, or another saved run.",
+	Long: `Compares a baseline saved with 'nethelp baseline save' against a new run
+("current", the default) or another saved run, and lists:
+  state    checks that flipped between reachable, unauthorized and failed
+  latency  reachable checks that got slower by more than --threshold
+  tls      TLS issuers the baseline never saw, often an intercepting proxy
+  dns      hosts whose DNS answers changed, for hosts the checks looked up locally
+  proxy    changed --proxy and proxy environment variables
+
+$ nethelp diff default
+$ nethelp diff before-firewall-change current --tcp
+$ nethelp diff ./monday.json ./tuesday.json`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		threshold, err := cmd.Flags().GetDuration("threshold")
+		if err != nil {
+			log.Fatal("Could not get the threshold flag. ", err)
+		}
+		baseline := loadBaseline(args[0])
+		var current snapshot.Snapshot
+		if len(args) == 1 || args[1] == "current" {
+			current = currentSnapshot(cmd)
+		} else {
+			current = loadBaseline(args[1])
+		}
+
+		changes := snapshot.Diff(baseline, current, threshold)
+		fmt.Printf("\nComparing %s (%s) with %s\n", args[0], baseline.Taken.Format(time.RFC1123), current.Taken.Format(time.RFC1123))
+		if len(changes) == 0 {
+			fmt.Println("No changes.")
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "KIND\tWHAT\tBASELINE\tNOW")
+		for _, c := range changes {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Kind, redact.String(c.Subject), redact.String(c.Before), redact.String(c.After))
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	addSelectionFlags(diffCmd.Flags())
+	diffCmd.Flags().Duration("threshold", 250*time.Millisecond, "report reachable checks that got slower by more than this.")
+}
+
+// loadBaseline reads a saved run by name or path
+func loadBaseline(name string) snapshot.Snapshot {
+	path, err := baselinePath(name)
+	if err != nil {
+		log.Fatal("Could not find the baselines directory. ", err)
+	}
+	snap, err := snapshot.Load(path)
+	if err != nil {
+		log.Fatalf("Could not load the baseline %s. %v", name, err)
+	}
+	return snap
+}
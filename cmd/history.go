@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mdsauce/nethelp/connections"
+	"github.com/mdsauce/nethelp/history"
+	"github.com/mdsauce/nethelp/redact"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List past runs kept in the local history.",
+	Long: `Every run keeps its structured results in the history directory of the
+nethelp config dir, unless --no-history is set.  Without a subcommand the
+most recent runs are listed.
+
+$ nethelp history
+$ nethelp history show last
+$ nethelp history timeline --runs 50`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		n, err := cmd.Flags().GetInt("runs")
+		if err != nil {
+			log.Fatal("Could not get the runs flag. ", err)
+		}
+		ids, runs, err := history.Last(n)
+		if err != nil {
+			log.Fatal("Could not read the history. ", err)
+		}
+		if len(runs) == 0 {
+			fmt.Println("No runs recorded yet.")
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTIME\tHOST\tPROXY\tCHECKS\tFAILED")
+		for i, run := range runs {
+			failed := 0
+			for _, r := range run.Results {
+				if r.Outcome == connections.Failed {
+					failed++
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\n", ids[i], run.Taken.Local().Format(time.RFC1123), run.Hostname, orDash(run.Proxy), len(run.Results), failed)
+		}
+		w.Flush()
+	},
+}
+
+// historyShowCmd represents the history show command
+var historyShowCmd = &cobra.Command{
+	Use:   "show <id|last>",
+	Short: "Print every check of one run from the history.",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		run, err := history.Load(args[0])
+		if err != nil {
+			log.Fatalf("Could not load the run %s. %v", args[0], err)
+		}
+		fmt.Printf("Run of %s on %s, nethelp %s\n", run.Taken.Local().Format(time.RFC1123), run.Hostname, run.Version)
+		fmt.Printf("Proxy: %s\n\n", orDash(run.Proxy))
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "GROUP\tCHECK\tENDPOINT\tOUTCOME\tSTATUS\tTIME\tDETAIL")
+		for _, r := range run.Results {
+			detail := r.Detail
+			if r.Error != "" {
+				detail = r.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Group, r.Check, r.Endpoint, r.Outcome, orDash(r.Status), r.Duration.Round(time.Millisecond), redact.String(detail))
+		}
+		w.Flush()
+	},
+}
+
+// historyTimelineCmd represents the history timeline command
+var historyTimelineCmd = &cobra.Command{
+	Use:   "timeline",
+	Short: "Show the availability of every endpoint across past runs.",
+	Long: `Prints one line per check with one mark per run, oldest on the left:
+  +  reachable
+  !  reachable, credentials rejected
+  x  failed
+  .  not part of that run`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		n, err := cmd.Flags().GetInt("runs")
+		if err != nil {
+			log.Fatal("Could not get the runs flag. ", err)
+		}
+		ids, runs, err := history.Last(n)
+		if err != nil {
+			log.Fatal("Could not read the history. ", err)
+		}
+		if len(runs) == 0 {
+			fmt.Println("No runs recorded yet.")
+			return
+		}
+		fmt.Printf("%d runs from %s to %s\n\n", len(ids), runs[0].Taken.Local().Format(time.RFC1123), runs[len(runs)-1].Taken.Local().Format(time.RFC1123))
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "CHECK\tAVAILABLE\tRUNS")
+		for _, row := range history.Timeline(runs) {
+			fmt.Fprintf(w, "%s\t%.0f%%\t%s\n", row.Key, row.Availability()*100, marks(row.Outcomes))
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyShowCmd)
+	historyCmd.AddCommand(historyTimelineCmd)
+	historyCmd.Flags().Int("runs", 20, "how many of the most recent runs to list.  0 lists all of them.")
+	historyTimelineCmd.Flags().Int("runs", 30, "how many of the most recent runs to line up.  0 uses all of them.")
+}
+
+// marks turns outcomes into one character per run
+func marks(outcomes []connections.Outcome) string {
+	var b strings.Builder
+	for _, o := range outcomes {
+		switch o {
+		case connections.Reachable:
+			b.WriteByte('+')
+		case connections.Unauthorized:
+			b.WriteByte('!')
+		case connections.Failed:
+			b.WriteByte('x')
+		default:
+			b.WriteByte('.')
+		}
+	}
+	return b.String()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
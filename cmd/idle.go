@@ -2,20 +2,38 @@ package cmd
 
 import (
 	"github.com/mdsauce/nethelp/idle"
+	"github.com/mdsauce/nethelp/ports"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 // idleCmd represents the idle command
 var idleCmd = &cobra.Command{
 	Use:   "idle",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
+	Short: "Run a server that waits before answering, or listens on many ports.",
+	Long: `Without flags starts an HTTP server on $PORT that answers /N after N seconds,
+to test how long a proxy keeps idle requests open.
+
+With --ports it listens on every port in the range instead and greets each
+connection with its port number, a target for 'nethelp ports':
+
+$ nethelp idle --ports 1-65535`,
 	Run: func(cmd *cobra.Command, args []string) {
-		idle.IdleServer()
+		spec, err := cmd.Flags().GetString("ports")
+		if err != nil {
+			log.Fatal("Could not get the ports flag. ", err)
+		}
+		if spec == "" {
+			idle.IdleServer()
+			return
+		}
+		list, err := ports.Parse(spec)
+		if err != nil {
+			log.Fatal("The port range is not valid. ", err)
+		}
+		if err := idle.EchoPorts(list); err != nil {
+			log.Fatal(err)
+		}
 	},
 }
 
@@ -31,4 +49,5 @@ func init() {
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
 	// idleCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	idleCmd.Flags().String("ports", "", "listen on every port in this range, e.g. 1-65535, and greet each connection with its port number.")
 }
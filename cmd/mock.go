@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"github.com/mdsauce/nethelp/mock"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// mockCmd represents the mock command
+var mockCmd = &cobra.Command{
+	Use:   "mock",
+	Short: "Run a local stand-in for the Sauce Labs endpoints nethelp tests.",
+	Long: `Starts an HTTP server that answers like the Sauce Labs services nethelp
+targets: the ondemand root, /wd/hub/status, /wd/hub/session,
+/rest/v1/{user}/tunnels and /rest/v1/users/{user}, plus /generate_204 for
+the captive portal probe.  Point a run at it with --base-url, for example:
+
+$ nethelp mock --port 8000 --username bob --access-key secret --fail-rate 0.2
+$ SAUCE_USERNAME=bob SAUCE_ACCESS_KEY=secret nethelp --base-url http://localhost:8000`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := mock.Config{}
+		var err error
+		if cfg.Port, err = cmd.Flags().GetString("port"); err != nil {
+			log.Fatal("Could not get the port flag. ", err)
+		}
+		if cfg.Username, err = cmd.Flags().GetString("username"); err != nil {
+			log.Fatal("Could not get the username flag. ", err)
+		}
+		if cfg.AccessKey, err = cmd.Flags().GetString("access-key"); err != nil {
+			log.Fatal("Could not get the access-key flag. ", err)
+		}
+		if cfg.Latency, err = cmd.Flags().GetDuration("latency"); err != nil {
+			log.Fatal("Could not get the latency flag. ", err)
+		}
+		if cfg.Jitter, err = cmd.Flags().GetDuration("jitter"); err != nil {
+			log.Fatal("Could not get the jitter flag. ", err)
+		}
+		if cfg.FailRate, err = cmd.Flags().GetFloat64("fail-rate"); err != nil {
+			log.Fatal("Could not get the fail-rate flag. ", err)
+		}
+		if cfg.FailStatus, err = cmd.Flags().GetInt("fail-status"); err != nil {
+			log.Fatal("Could not get the fail-status flag. ", err)
+		}
+		if cfg.Portal, err = cmd.Flags().GetString("captive-portal"); err != nil {
+			log.Fatal("Could not get the captive-portal flag. ", err)
+		}
+		if cfg.ClockOffset, err = cmd.Flags().GetDuration("clock-offset"); err != nil {
+			log.Fatal("Could not get the clock-offset flag. ", err)
+		}
+		if cfg.FailRate < 0 || cfg.FailRate > 1 {
+			log.Fatal("The fail-rate must be between 0 and 1.")
+		}
+		mock.MockServer(cfg)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mockCmd)
+
+	mockCmd.Flags().String("port", "8000", "port for the mock server to listen on.")
+	mockCmd.Flags().String("username", "", "username the REST endpoints accept.  Leave empty to disable authentication.")
+	mockCmd.Flags().String("access-key", "", "access key the REST endpoints accept.")
+	mockCmd.Flags().Duration("latency", 0, "delay added to every response, e.g. 250ms.")
+	mockCmd.Flags().Duration("jitter", 0, "random extra delay of up to this duration added to every response.")
+	mockCmd.Flags().Float64("fail-rate", 0, "share of requests between 0 and 1 that fail on purpose.")
+	mockCmd.Flags().String("captive-portal", "", "redirect every request to this login page, like the captive portal of a hotel network.")
+	mockCmd.Flags().Duration("clock-offset", 0, "move the Date header of every response by this much, e.g. -10m, to play a machine with a wrong clock.")
+	mockCmd.Flags().Int("fail-status", 503, "HTTP status returned by failed requests.  Use 0 to drop the connection instead.")
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/mdsauce/nethelp/ports"
+	"github.com/mdsauce/nethelp/proxy"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// portsCmd represents the ports command
+var portsCmd = &cobra.Command{
+	Use:   "ports",
+	Short: "Find out which outbound TCP ports your firewall lets through.",
+	Long: `Connects to every port in --range on --target and sorts each port into
+open, closed (refused, so the packets got out) or filtered (dropped or reset on the way).
+Run 'nethelp idle --ports 1-65535' on a host you own outside the firewall to
+have a target that listens on every port, nethelp then also proves each open
+port reached that host and not a middlebox.  With --proxy every connection
+is tunneled through the proxy, which shows the ports the proxy lets through.
+
+$ nethelp ports --target echo.example.com --range 1-65535 --concurrency 200 --rate 1000`,
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := ports.Options{}
+		var err error
+		if opts.Target, err = cmd.Flags().GetString("target"); err != nil {
+			log.Fatal("Could not get the target flag. ", err)
+		}
+		if opts.Target == "" {
+			log.Fatal("--target is required, e.g. --target echo.example.com")
+		}
+		spec, err := cmd.Flags().GetString("range")
+		if err != nil {
+			log.Fatal("Could not get the range flag. ", err)
+		}
+		if opts.Ports, err = ports.Parse(spec); err != nil {
+			log.Fatal("The port range is not valid. ", err)
+		}
+		if opts.Concurrency, err = cmd.Flags().GetInt("concurrency"); err != nil {
+			log.Fatal("Could not get the concurrency flag. ", err)
+		}
+		if opts.Rate, err = cmd.Flags().GetInt("rate"); err != nil {
+			log.Fatal("Could not get the rate flag. ", err)
+		}
+		if opts.Timeout, err = cmd.Flags().GetDuration("timeout"); err != nil {
+			log.Fatal("Could not get the timeout flag. ", err)
+		}
+		if userProxy != "" {
+			proxyURL, err := proxy.Parse(userProxy)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if opts.Dial, err = proxy.Dialer(proxyURL, (&net.Dialer{}).DialContext); err != nil {
+				log.Fatal("Could not dial through the proxy. ", err)
+			}
+			fmt.Printf("Sweeping through the %s proxy %s, the proxy decides which ports it tunnels.\n", proxyURL.Scheme, proxyURL.Host)
+		}
+		start := time.Now()
+		opts.Progress = func(done, total int) {
+			if done%1000 == 0 || done == total {
+				fmt.Fprintf(os.Stderr, "\r%d/%d ports", done, total)
+			}
+		}
+
+		ctx, stop := context.WithCancel(context.Background())
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		go func() {
+			<-interrupt
+			stop()
+		}()
+		results := ports.Sweep(ctx, opts)
+		fmt.Fprintln(os.Stderr)
+
+		byState := make(map[ports.State][]int)
+		var echoed []int
+		for _, r := range results {
+			byState[r.State] = append(byState[r.State], r.Port)
+			if r.Echo {
+				echoed = append(echoed, r.Port)
+			}
+		}
+		fmt.Printf("Swept %d of %d port(s) on %s in %s\n", len(results), len(opts.Ports), opts.Target, time.Since(start).Round(time.Second))
+		for _, state := range []ports.State{ports.Open, ports.Closed, ports.Filtered} {
+			fmt.Printf("%-9s %5d  %s\n", state, len(byState[state]), ports.Ranges(byState[state]))
+		}
+		if len(echoed) > 0 {
+			fmt.Printf("%d open port(s) answered with the nethelp banner: %s\n", len(echoed), ports.Ranges(echoed))
+			if intercepted := len(byState[ports.Open]) - len(echoed); intercepted > 0 {
+				fmt.Printf("[ ] %d open port(s) did not, something in between accepted those connections.\n", intercepted)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(portsCmd)
+
+	portsCmd.Flags().String("target", "", "host to sweep, ideally one running 'nethelp idle --ports'.")
+	portsCmd.Flags().String("range", "1-1024", "ports to try, e.g. 1-65535 or 80,443,8000-8100.")
+	portsCmd.Flags().Int("concurrency", 100, "connections open at the same time.")
+	portsCmd.Flags().Int("rate", 500, "new connections per second at most, 0 for no limit.  Keep it low enough for your firewall not to flag a port scan.")
+	portsCmd.Flags().Duration("timeout", 3*time.Second, "how long to wait for every connection.")
+}
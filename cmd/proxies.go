@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mdsauce/nethelp/proxy"
+	"github.com/mdsauce/nethelp/redact"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// proxiesCmd represents the proxies command
+var proxiesCmd = &cobra.Command{
+	Use:   "proxies",
+	Short: "List the proxies the tools on this machine are configured with.",
+	Long: `Reads the proxy settings Java, Maven, npm, git, pip, Docker and the
+environment pick up, then reports where they disagree with each other or
+with --proxy.  Test suites in different languages that fail differently
+often come down to one of these.
+
+$ nethelp proxies --proxy http://proxy.inc.com:8080`,
+	Run: func(cmd *cobra.Command, args []string) {
+		proxyURL, err := proxy.Parse(userProxy)
+		if err != nil {
+			log.Fatal(err)
+		}
+		found := proxy.Discover()
+		if len(found) == 0 {
+			fmt.Println("No proxy settings found.")
+		} else {
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "SOURCE\tSETTING\tPROXY")
+			for _, s := range found {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", s.Source, s.Key, redact.String(s.Value))
+			}
+			w.Flush()
+		}
+		problems := proxy.Inconsistencies(found, proxyURL)
+		if len(problems) > 0 {
+			fmt.Println()
+		}
+		for _, problem := range problems {
+			redact.Printf("[ ] %s\n", problem)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(proxiesCmd)
+}
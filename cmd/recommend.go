@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mdsauce/nethelp/recommend"
+	"github.com/mdsauce/nethelp/suite"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// recommendCmd represents the recommend command
+var recommendCmd = &cobra.Command{
+	Use:   "recommend",
+	Short: "Rank the data centers by latency and recommend one for --dc.",
+	Long: `Opens repeated TCP connections with a TLS handshake to the ondemand host
+and the API host of every data center, then ranks the data centers by the
+median connect plus TLS time.  Data centers that fail more than 10% of the
+handshakes rank last, by their failure rate.
+
+$ nethelp recommend --samples 10`,
+	Run: func(cmd *cobra.Command, args []string) {
+		samples, err := cmd.Flags().GetInt("samples")
+		if err != nil {
+			log.Fatal("Could not get the samples flag. ", err)
+		}
+		if samples < 1 {
+			log.Fatal("--samples must be at least 1.")
+		}
+		opts := suite.Options{}
+		networkFromFlags(cmd, &opts)
+		scores, err := suite.Recommend(context.Background(), opts, samples)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "RANK\tDC\tNAME\tHOST\tCONNECT p50 (min-max)\tTLS p50 (min-max)\tFAILED")
+		for i, s := range scores {
+			for j, h := range s.Hosts {
+				rank, dc, name := "", "", ""
+				if j == 0 {
+					rank, dc, name = fmt.Sprint(i+1), s.Region.Key, s.Region.Name
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s %s\t%s\t%s\t%d/%d\n", rank, dc, name, h.Role, h.Addr, spread(h.Connect), spread(h.TLS), h.Failed, h.Samples)
+			}
+		}
+		w.Flush()
+		fmt.Println()
+
+		if len(scores) == 0 || scores[0].Failed > 0 && scores[0].Total == 0 {
+			fmt.Println("[ ] No data center could be reached.  Run 'nethelp --tcp' to find out why.")
+			return
+		}
+		best := scores[0]
+		fmt.Printf("Recommended: --dc %s (%s).  Connect plus TLS takes %s at the median", best.Region.Key, best.Region.Name, ms(best.Total))
+		if len(scores) > 1 && scores[1].Failed == 0 {
+			fmt.Printf(", %s less than %s (%s)", ms(scores[1].Total.Round(time.Millisecond)-best.Total.Round(time.Millisecond)), scores[1].Region.Key, ms(scores[1].Total))
+		}
+		fmt.Println(".")
+		if best.Failed > 0 {
+			fmt.Printf("[ ] %d of %d handshake(s) with %s failed.  Check the FAILED column.\n", best.Failed, best.Samples, best.Region.Key)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(recommendCmd)
+
+	recommendCmd.Flags().Int("samples", 5, "handshakes per host.  More samples smooth out a noisy network.")
+}
+
+// spread prints the median with the range of the samples
+func spread(s recommend.Stats) string {
+	if s.Max == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%s (%s-%s)", ms(s.Median), ms(s.Min), ms(s.Max))
+}
+
+func ms(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mdsauce/nethelp/endpoints"
+	"github.com/spf13/cobra"
+)
+
+// regionsCmd represents the regions command
+var regionsCmd = &cobra.Command{
+	Use:   "regions",
+	Short: "List the data centers and hosts nethelp knows about.",
+	Long: `Prints every data center from the built-in region registry with the
+hosts, ports and REST API base of each cloud.  The DC column is the value
+to pass to --dc; the aliases are accepted as well.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "DC\tNAME\tCLOUD\tHOST\tPORTS\tREST API")
+		for _, r := range endpoints.Regions() {
+			dc := r.Key
+			if len(r.Aliases) > 0 {
+				dc = fmt.Sprintf("%s (%s)", r.Key, strings.Join(r.Aliases, ", "))
+			}
+			clouds := make([]string, 0, len(r.Clouds))
+			for name := range r.Clouds {
+				clouds = append(clouds, name)
+			}
+			sort.Strings(clouds)
+			for _, name := range clouds {
+				c := r.Clouds[name]
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", dc, r.Name, name, c.Host, joinPorts(c.Ports), c.REST)
+			}
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(regionsCmd)
+}
+
+func joinPorts(ports []int) string {
+	s := make([]string, len(ports))
+	for i, p := range ports {
+		s[i] = fmt.Sprint(p)
+	}
+	return strings.Join(s, ",")
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mdsauce/nethelp/connections"
+	"github.com/mdsauce/nethelp/diagnose"
+	"github.com/mdsauce/nethelp/endpoints"
+	"github.com/mdsauce/nethelp/history"
+	"github.com/mdsauce/nethelp/proxy"
+	"github.com/mdsauce/nethelp/redact"
+	"github.com/mdsauce/nethelp/report"
+	"github.com/mdsauce/nethelp/snapshot"
+	"github.com/mdsauce/nethelp/suite"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// addSelectionFlags defines the flags that decide which diagnostics to run
+func addSelectionFlags(flags *pflag.FlagSet) {
+	flags.Bool("tcp", false, "run TCP tests. Will always run against all endpoints.")
+	flags.String("cloud", "all", "options are: VDC, RDC, or HEADLESS.  Select which services you'd like to test, Virtual Device Cloud, Real Device Cloud, or the Headless Cloud.")
+	flags.Bool("session-test", false, "start a real WebDriver session with your credentials on every selected VDC and RDC data center, run one command, then delete it.  Uses your Sauce Labs minutes.")
+	flags.String("dc", "all", dcHelp())
+	flags.Bool("methods", false, "send GET, POST, PUT and DELETE with and without a body and with Expect: 100-continue to every selected VDC and RDC hub.")
+	flags.String("echo-url", "", "also send the method matrix to this echo target, the /echo path of 'nethelp mock' or 'nethelp idle'.  Defaults to --base-url.")
+	flags.Bool("integrity", false, "download a payload of known size and checksum with and without gzip and verify it arrived intact.  Uses the /payload of --base-url unless --integrity-url is set.")
+	flags.String("integrity-url", "", "source of the integrity check, e.g. http://host:8080/payload?size=10485760 on 'nethelp idle'.  Implies --integrity.")
+	flags.Int64("integrity-size", 0, "size in bytes of the --integrity-url payload.  Not needed for the /payload of 'nethelp mock' or 'nethelp idle'.")
+	flags.String("integrity-sha256", "", "hex SHA-256 of the --integrity-url payload.")
+	flags.Bool("websocket", false, "open a WebSocket through the proxy, send one message and expect it back.  Uses the /ws path of --base-url unless --websocket-url is set.")
+	flags.String("websocket-url", "", "echo target of the WebSocket check, e.g. ws://host:8080/ws on 'nethelp idle'.  Implies --websocket.")
+	flags.StringArray("checks", nil, "also run the user defined checks in this YAML file.  Results show up under the group the file names, or 'custom'.  Can be repeated.")
+}
+
+// selectionFromFlags collects the flags that decide which diagnostics to run
+func selectionFromFlags(cmd *cobra.Command, opts *suite.Options) {
+	var err error
+	if opts.TCP, err = cmd.Flags().GetBool("tcp"); err != nil {
+		log.Fatal("Could not get the TCP flag. ", err)
+	}
+	if opts.DC, err = cmd.Flags().GetString("dc"); err != nil {
+		log.Fatal("Could not get the dc flag. ", err)
+	}
+	if opts.Cloud, err = cmd.Flags().GetString("cloud"); err != nil {
+		log.Fatal("Could not get the cloud flag. ", err)
+	}
+	if opts.SessionTest, err = cmd.Flags().GetBool("session-test"); err != nil {
+		log.Fatal("Could not get the session-test flag. ", err)
+	}
+	if opts.Methods, err = cmd.Flags().GetBool("methods"); err != nil {
+		log.Fatal("Could not get the methods flag. ", err)
+	}
+	if opts.EchoURL, err = cmd.Flags().GetString("echo-url"); err != nil {
+		log.Fatal("Could not get the echo-url flag. ", err)
+	}
+	if opts.Integrity, err = cmd.Flags().GetBool("integrity"); err != nil {
+		log.Fatal("Could not get the integrity flag. ", err)
+	}
+	if opts.IntegrityURL, err = cmd.Flags().GetString("integrity-url"); err != nil {
+		log.Fatal("Could not get the integrity-url flag. ", err)
+	}
+	if opts.IntegritySize, err = cmd.Flags().GetInt64("integrity-size"); err != nil {
+		log.Fatal("Could not get the integrity-size flag. ", err)
+	}
+	if opts.IntegritySHA256, err = cmd.Flags().GetString("integrity-sha256"); err != nil {
+		log.Fatal("Could not get the integrity-sha256 flag. ", err)
+	}
+	opts.Integrity = opts.Integrity || opts.IntegrityURL != ""
+	if opts.WebSocket, err = cmd.Flags().GetBool("websocket"); err != nil {
+		log.Fatal("Could not get the websocket flag. ", err)
+	}
+	if opts.WebSocketURL, err = cmd.Flags().GetString("websocket-url"); err != nil {
+		log.Fatal("Could not get the websocket-url flag. ", err)
+	}
+	opts.WebSocket = opts.WebSocket || opts.WebSocketURL != ""
+	checkFiles, err := cmd.Flags().GetStringArray("checks")
+	if err != nil {
+		log.Fatal("Could not get the checks flag. ", err)
+	}
+	for _, path := range checkFiles {
+		custom, err := endpoints.LoadCustom(path)
+		if err != nil {
+			log.Fatal("Could not load the user defined checks. ", err)
+		}
+		opts.Custom = append(opts.Custom, custom)
+	}
+	// --tcp on its own only runs the TCP tests
+	opts.HTTP = !opts.TCP || opts.Cloud != "all"
+}
+
+// networkFromFlags applies --proxy, --lucky, --base-url, --resolve,
+// --connect-to and the credential flags to opts
+func networkFromFlags(cmd *cobra.Command, opts *suite.Options) {
+	var err error
+	opts.Proxy = userProxy
+	if opts.SkipProxyCheck, err = cmd.Flags().GetBool("lucky"); err != nil {
+		log.Fatal("Something went terribly wrong disabling the check with --lucky", err)
+	}
+	if opts.BaseURL, err = cmd.Flags().GetString("base-url"); err != nil {
+		log.Fatal("Could not get the base-url flag. ", err)
+	}
+	if opts.ConnectTo, err = cmd.Flags().GetStringArray("connect-to"); err != nil {
+		log.Fatal("Could not get the connect-to flag. ", err)
+	}
+	if opts.Resolve, err = cmd.Flags().GetStringArray("resolve"); err != nil {
+		log.Fatal("Could not get the resolve flag. ", err)
+	}
+	if opts.HTTPVersion, err = cmd.Flags().GetString("http-version"); err != nil {
+		log.Fatal("Could not get the http-version flag. ", err)
+	}
+	probe, err := cmd.Flags().GetString("portal-probe")
+	if err != nil {
+		log.Fatal("Could not get the portal-probe flag. ", err)
+	}
+	// the suite picks the default probe itself, so it follows --base-url
+	switch probe {
+	case "":
+		opts.SkipPortalProbe = true
+	case connections.DefaultPortalProbe:
+	default:
+		opts.PortalProbe = probe
+	}
+	opts.Credentials = credentialsProvider(cmd)
+	opts.Out = os.Stdout
+}
+
+// runSuite runs opts and exits on anything that stops the run from starting
+func runSuite(opts suite.Options) *suite.Report {
+	rep, err := suite.Run(context.Background(), opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return rep
+}
+
+// runAndRecord runs opts and keeps the run in the history unless --no-history
+// is set.  The snapshot is only taken, with a DNS lookup of every host, when
+// the run is recorded or snap is set, and is empty otherwise.
+func runAndRecord(cmd *cobra.Command, opts suite.Options, snap bool) (*suite.Report, snapshot.Snapshot) {
+	rep := runSuite(opts)
+	skip, err := cmd.Flags().GetBool("no-history")
+	if err != nil {
+		log.Fatal("Could not get the no-history flag. ", err)
+	}
+	if skip && !snap {
+		return rep, snapshot.Snapshot{}
+	}
+	s := snapshot.Take(context.Background(), rep, Version)
+	if !skip {
+		id, err := history.Record(s)
+		if err != nil {
+			log.Warn("Could not record the run in the history. ", err)
+		} else {
+			log.Info("Run recorded in the history as ", id)
+		}
+	}
+	return rep, s
+}
+
+// printDiagnosis explains the failures of a run with next steps
+func printDiagnosis(rep *suite.Report) {
+	diagnoses := diagnose.Diagnose(rep.Results, rep.Proxy != "")
+	if len(diagnoses) == 0 {
+		return
+	}
+	fmt.Println("\nDiagnosis:")
+	for _, d := range diagnoses {
+		redact.Printf("[%s] %s\n", d.Cause, d.Summary)
+		for _, step := range d.Steps {
+			redact.Printf("    - %s\n", step)
+		}
+	}
+}
+
+// htmlReport renders the results of a run as a single HTML page
+func htmlReport(w io.Writer, rep *suite.Report) error {
+	return report.WriteHTML(w, report.Report{
+		Generated:  time.Now(),
+		Version:    Version,
+		Proxy:      rep.Proxy,
+		EnvProxies: proxy.EnvProxies(),
+		Results:    rep.Results,
+	})
+}
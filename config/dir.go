@@ -0,0 +1,19 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Dir returns the directory nethelp keeps its files in, usually
+// ~/.config/nethelp on Linux.  NETHELP_CONFIG_DIR overrides it.
+func Dir() (string, error) {
+	if dir := os.Getenv("NETHELP_CONFIG_DIR"); dir != "" {
+		return dir, nil
+	}
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "nethelp"), nil
+}
@@ -0,0 +1,122 @@
+package connections
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// account is the part of /rest/v1/users/{user} nethelp cares about
+type account struct {
+	ID               string         `json:"id"`
+	Username         string         `json:"username"`
+	UserType         string         `json:"user_type"`
+	ConcurrencyLimit map[string]int `json:"concurrency_limit"`
+}
+
+// apiOutput checks that a REST API response proves the credentials work,
+// not only that the API is reachable.  401 and 403 are reported on their own
+// so a wrong access key is never mistaken for success.  Returns the verdict,
+// a short account or error summary and the cause of a failure it knows.
+// res carries what fromResponse found.
+func (r *Runner) apiOutput(resp *http.Response, endpoint, username string, res Result) (Outcome, string, Cause) {
+	defer resp.Body.Close()
+	shown := endpoint + r.remapNote(endpoint)
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		r.printf("%s %s is reachable but rejected the credentials for %s (%s).  Check the username and access key for this data center.\n", markAuth, shown, username, resp.Status)
+		log.WithFields(log.Fields{
+			"status":   resp.Status,
+			"username": username,
+		}).Infof("%s %s reachable but unauthorized.\n", markAuth, endpoint)
+		return Unauthorized, "credentials rejected for " + username, ""
+	}
+	if resp.StatusCode != http.StatusOK {
+		r.respOutput(resp, endpoint, res)
+		return Failed, "", ""
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		r.printf("%s %s returned %s but the body could not be read: %v\n", markFail, shown, resp.Status, err)
+		return Failed, err.Error(), ""
+	}
+	if strings.Contains(resp.Request.URL.Path, "/tunnels") {
+		var tunnels []interface{}
+		if err := json.Unmarshal(body, &tunnels); err != nil {
+			return Failed, r.notJSON(shown, "Sauce Labs API JSON", resp, body, err), CauseProxyBlock
+		}
+		r.printf("%s %s accepted the credentials for %s, %d tunnel(s) running\n", markOK, shown, username, len(tunnels))
+		log.WithFields(log.Fields{
+			"status":  resp.Status,
+			"tunnels": tunnels,
+		}).Infof("%s %s authenticated.\n", markOK, endpoint)
+		return Reachable, fmt.Sprintf("%d tunnel(s) running", len(tunnels)), ""
+	}
+
+	var acct account
+	if err := json.Unmarshal(body, &acct); err != nil {
+		return Failed, r.notJSON(shown, "Sauce Labs API JSON", resp, body, err), CauseProxyBlock
+	}
+	returned := acct.Username
+	if returned == "" {
+		returned = acct.ID
+	}
+	if !strings.EqualFold(returned, username) {
+		r.printf("%s %s authenticated as %q instead of %q\n", markFail, shown, returned, username)
+		log.WithFields(log.Fields{
+			"status":   resp.Status,
+			"expected": username,
+			"returned": returned,
+		}).Infof("%s %s returned a different user.\n", markFail, endpoint)
+		return Failed, fmt.Sprintf("authenticated as %q instead of %q", returned, username), ""
+	}
+	r.printf("%s %s accepted the credentials for %s%s\n", markOK, shown, username, accountDetails(acct))
+	log.WithFields(log.Fields{
+		"status":            resp.Status,
+		"user_type":         acct.UserType,
+		"concurrency_limit": acct.ConcurrencyLimit,
+	}).Infof("%s %s authenticated.\n", markOK, endpoint)
+	return Reachable, strings.TrimSuffix(strings.TrimPrefix(accountDetails(acct), " ("), ")"), ""
+}
+
+// accountDetails summarizes the account type and concurrency limits
+func accountDetails(acct account) string {
+	var details []string
+	if acct.UserType != "" {
+		details = append(details, "type "+acct.UserType)
+	}
+	limits := make([]string, 0, len(acct.ConcurrencyLimit))
+	for k := range acct.ConcurrencyLimit {
+		limits = append(limits, k)
+	}
+	sort.Strings(limits)
+	for _, k := range limits {
+		details = append(details, fmt.Sprintf("%s concurrency %d", k, acct.ConcurrencyLimit[k]))
+	}
+	if len(details) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(details, ", ") + ")"
+}
+
+// notJSON reports a 200 whose body is not the expected kind of JSON,
+// usually a proxy or captive portal answering in place of Sauce Labs
+func (r *Runner) notJSON(shown, kind string, resp *http.Response, body []byte, err error) string {
+	r.printf("%s %s returned %s but not %s.  Something between you and Sauce Labs may have answered instead.\n", markFail, shown, resp.Status, kind)
+	snippet := string(body)
+	if len(snippet) > 200 {
+		snippet = snippet[:200]
+	}
+	log.WithFields(log.Fields{
+		"error":        err,
+		"content-type": resp.Header.Get("Content-Type"),
+		"body":         snippet,
+	}).Infof("%s %s returned invalid JSON.\n", markFail, shown)
+	return "response is not " + kind
+}
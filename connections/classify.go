@@ -0,0 +1,162 @@
+package connections
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+
+	"github.com/mdsauce/nethelp/proxy"
+)
+
+// Cause is the likely root cause of a failed check
+type Cause string
+
+// Every failure is sorted into one of these
+const (
+	CauseDNS         Cause = "dns"
+	CauseRefused     Cause = "refused"
+	CauseTimeout     Cause = "timeout"
+	CauseReset       Cause = "reset"
+	CauseTLS         Cause = "tls"
+	CauseProxyAuth   Cause = "proxy-auth"
+	CauseProxyBlock  Cause = "proxy-block"
+	CauseCaptive     Cause = "captive-portal"
+	CauseRedirect    Cause = "redirect"
+	CauseClock       Cause = "clock"
+	CauseHTTP2       Cause = "http2"
+	CauseIntegrity   Cause = "integrity"
+	CauseUpstream5xx Cause = "upstream-5xx"
+	CauseOther       Cause = "other"
+)
+
+// causePatterns match the text of Go network errors, for errors that
+// lost their type and for results read back from disk.  The errors of
+// the checks themselves carry their cause, see withCause.
+var causePatterns = []struct {
+	cause    Cause
+	patterns []string
+}{
+	{CauseProxyAuth, []string{"proxy authentication required"}},
+	{CauseDNS, []string{"no such host", "server misbehaving"}},
+	{CauseRefused, []string{"connection refused", "actively refused"}},
+	{CauseTimeout, []string{"i/o timeout", "deadline exceeded", "timeout awaiting", "client.timeout exceeded", "tls handshake timeout"}},
+	{CauseReset, []string{"connection reset", "broken pipe", "forcibly closed"}},
+	{CauseTLS, []string{"tls:", "x509:", "http response to https client"}},
+}
+
+// causeError is an error whose cause the check that created it knows
+type causeError struct {
+	cause Cause
+	err   error
+}
+
+func (e *causeError) Error() string { return e.err.Error() }
+func (e *causeError) Unwrap() error { return e.err }
+
+// withCause marks err with the cause it stands for
+func withCause(cause Cause, err error) error {
+	return &causeError{cause: cause, err: err}
+}
+
+// Classify sorts a failed result into a root cause.  Block pages and
+// redirects come first, then the cause the check set, then the type of
+// the error it failed with.  The error text and status code are the
+// fallback.  Results that did not fail have no cause.
+func Classify(r Result) Cause {
+	if r.Outcome != Failed {
+		return ""
+	}
+	switch r.Check {
+	case "clock":
+		return CauseClock
+	case "http2":
+		return CauseHTTP2
+	}
+	if r.BlockPage {
+		return CauseProxyBlock
+	}
+	if r.OffSite != "" && r.Check != "captive-portal" {
+		return CauseRedirect
+	}
+	if r.Cause != "" {
+		return r.Cause
+	}
+	if cause := classifyErr(r.err); cause != "" {
+		return cause
+	}
+	text := strings.ToLower(r.Error)
+	if text == "" {
+		text = strings.ToLower(r.Detail)
+	}
+	for _, c := range causePatterns {
+		for _, p := range c.patterns {
+			if strings.Contains(text, p) {
+				return c.cause
+			}
+		}
+	}
+	if text == "eof" || strings.HasSuffix(text, ": eof") {
+		return CauseReset
+	}
+	if cause := statusCause(r.StatusCode); cause != "" {
+		return cause
+	}
+	return CauseOther
+}
+
+// classifyErr sorts an error by its type, empty when the type tells nothing
+func classifyErr(err error) Cause {
+	var (
+		marked    *causeError
+		refused   *proxy.RefusedError
+		dnsErr    *net.DNSError
+		netErr    net.Error
+		authority x509.UnknownAuthorityError
+		invalid   x509.CertificateInvalidError
+		hostname  x509.HostnameError
+		record    tls.RecordHeaderError
+	)
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &marked):
+		return marked.cause
+	case errors.As(err, &refused):
+		if refused.Auth {
+			return CauseProxyAuth
+		}
+		return CauseProxyBlock
+	case errors.As(err, &dnsErr):
+		return CauseDNS
+	case errors.As(err, &authority), errors.As(err, &invalid), errors.As(err, &hostname), errors.As(err, &record):
+		return CauseTLS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return CauseRefused
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return CauseTimeout
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNABORTED), errors.Is(err, syscall.EPIPE), errors.Is(err, io.EOF):
+		// a bare EOF is the server hanging up before it answered
+		return CauseReset
+	}
+	return ""
+}
+
+// statusCause is the cause a status code points to, empty for most codes
+func statusCause(code int) Cause {
+	switch {
+	case code == http.StatusProxyAuthRequired:
+		return CauseProxyAuth
+	case code == http.StatusForbidden:
+		// Sauce Labs endpoints answer 200 or 401, a 403 comes from something in the middle
+		return CauseProxyBlock
+	case code >= 500:
+		return CauseUpstream5xx
+	}
+	return ""
+}
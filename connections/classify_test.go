@@ -0,0 +1,65 @@
+package connections
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/mdsauce/nethelp/proxy"
+)
+
+func TestClassify(t *testing.T) {
+	urlErr := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://ondemand.saucelabs.com", Err: err}
+	}
+	dialErr := func(errno syscall.Errno) error {
+		return &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", errno)}
+	}
+	tests := []struct {
+		name string
+		res  Result
+		err  error
+		want Cause
+	}{
+		{name: "dns", err: urlErr(&net.DNSError{Err: "no such host", Name: "ondemand.saucelabs.com", IsNotFound: true}), want: CauseDNS},
+		{name: "refused", err: urlErr(dialErr(syscall.ECONNREFUSED)), want: CauseRefused},
+		{name: "reset", err: urlErr(dialErr(syscall.ECONNRESET)), want: CauseReset},
+		{name: "server hung up", err: urlErr(io.EOF), want: CauseReset},
+		{name: "unexpected EOF is not a reset", err: urlErr(io.ErrUnexpectedEOF), want: CauseOther},
+		{name: "deadline", err: urlErr(context.DeadlineExceeded), want: CauseTimeout},
+		{name: "proxy wants credentials", err: &proxy.RefusedError{Reason: "proxy answered CONNECT with 407", Auth: true}, want: CauseProxyAuth},
+		{name: "proxy refuses the tunnel", err: &proxy.RefusedError{Reason: "SOCKS4 proxy refused"}, want: CauseProxyBlock},
+		{name: "cause set by the check", err: withCause(CauseIntegrity, fmt.Errorf("received %d bytes of %d", 10, 20)), want: CauseIntegrity},
+		{name: "cause survives wrapping", err: fmt.Errorf("download: %w", withCause(CauseIntegrity, errors.New("cut off"))), want: CauseIntegrity},
+		{name: "check wording is not matched", err: errors.New("received 10 bytes of 20"), want: CauseOther},
+		{name: "text of a Go error read from disk", res: Result{Error: "dial tcp: lookup ondemand.saucelabs.com: no such host"}, want: CauseDNS},
+		{name: "explicit cause", res: Result{Cause: CauseProxyBlock, Detail: "response is not WebDriver status JSON"}, want: CauseProxyBlock},
+		{name: "block page beats the cause", res: Result{BlockPage: true, Cause: CauseOther}, want: CauseProxyBlock},
+		{name: "redirect", res: Result{OffSite: "https://login.hotel.example/"}, want: CauseRedirect},
+		{name: "captive portal", res: Result{Check: "captive-portal", OffSite: "https://login.hotel.example/", Cause: CauseCaptive}, want: CauseCaptive},
+		{name: "blocked portal probe", res: Result{Check: "captive-portal", StatusCode: 403}, want: CauseProxyBlock},
+		{name: "407", res: Result{StatusCode: 407}, want: CauseProxyAuth},
+		{name: "502", res: Result{StatusCode: 502}, want: CauseUpstream5xx},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := tt.res
+			if tt.err != nil {
+				r.fail(tt.err)
+			}
+			r.Outcome = Failed
+			if got := Classify(r); got != tt.want {
+				t.Errorf("Classify() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if got := Classify(Result{Outcome: Reachable, StatusCode: 502}); got != "" {
+		t.Errorf("Classify() of a reachable result = %q, want none", got)
+	}
+}
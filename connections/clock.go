@@ -0,0 +1,103 @@
+package connections
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SkewLimit is how far the local clock may be off.  Signed requests like
+// AWS signatures and Kerberos tickets are rejected beyond it, and TLS
+// fails long before a certificate expires once the clock is off by days.
+const SkewLimit = 5 * time.Minute
+
+// clockFromResponse measures the offset between the Date header of resp
+// and the local clock, and checks the certificate against the local time
+func (r *Result) clockFromResponse(resp *http.Response) {
+	now := time.Now()
+	if date := resp.Header.Get("Date"); date != "" {
+		if t, err := http.ParseTime(date); err == nil {
+			r.ServerDate = date
+			r.ClockSkew = t.Sub(now).Round(time.Second)
+		}
+	}
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		r.CertWindow = certWindow(resp.TLS.PeerCertificates[0], now)
+	}
+}
+
+// certWindow describes the validity of cert if now lies outside of it.
+// Only a run through a proxy skips verification and gets to see such a certificate.
+func certWindow(cert *x509.Certificate, now time.Time) string {
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return fmt.Sprintf("valid from %s until %s", cert.NotBefore.UTC().Format(time.RFC3339), cert.NotAfter.UTC().Format(time.RFC3339))
+	}
+	return ""
+}
+
+// ClockCheck compares the local clock with the Date headers of every
+// response so far.  A clock off by more than SkewLimit fails the check, and
+// certificate errors the skew explains are moved from tls to clock.
+func (r *Runner) ClockCheck() {
+	r.mu.Lock()
+	var skews []time.Duration
+	var certs []string
+	for _, res := range r.results {
+		if res.ServerDate != "" {
+			skews = append(skews, res.ClockSkew)
+		}
+		if res.CertWindow != "" {
+			certs = append(certs, fmt.Sprintf("%s is %s", res.Endpoint, res.CertWindow))
+		}
+	}
+	r.mu.Unlock()
+	if len(skews) == 0 {
+		return
+	}
+	// the median ignores a single proxy or block page with a wrong clock
+	sort.Slice(skews, func(i, j int) bool { return skews[i] < skews[j] })
+	skew := skews[len(skews)/2]
+
+	res := Result{Group: "network", Check: "clock", Endpoint: "local clock", Outcome: Reachable, ClockSkew: skew}
+	res.Detail = fmt.Sprintf("local clock is %s of %d server(s)", describeSkew(skew), len(skews))
+	if abs(skew) < SkewLimit {
+		r.record(res)
+		return
+	}
+	res.Outcome = Failed
+	res.Error = "clock skew of " + abs(skew).String()
+	r.printf("%s The %s.  TLS and signed requests fail beyond %s, sync the clock with NTP.\n", markFail, res.Detail, SkewLimit)
+	for _, cert := range certs {
+		r.printf("    %s at the local time %s\n", cert, time.Now().UTC().Format(time.RFC3339))
+	}
+	r.record(res)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, prev := range r.results {
+		if prev.Cause == CauseTLS && (prev.CertWindow != "" || strings.Contains(prev.Error, "expired or is not yet valid")) {
+			r.results[i].Cause = CauseClock
+		}
+	}
+}
+
+// describeSkew says which way the local clock is off
+func describeSkew(skew time.Duration) string {
+	switch {
+	case skew > 0:
+		return fmt.Sprintf("%s behind the clock", skew)
+	case skew < 0:
+		return fmt.Sprintf("%s ahead of the clock", -skew)
+	}
+	return "in sync with the clock"
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
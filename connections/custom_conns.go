@@ -0,0 +1,160 @@
+package connections
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mdsauce/nethelp/endpoints"
+	log "github.com/sirupsen/logrus"
+)
+
+// CustomChecks runs user defined checks and records them under their group.
+// Address checks only open a TCP connection, URL checks send the request
+// and evaluate the expected status, body regex and JSON path.
+func (r *Runner) CustomChecks(custom endpoints.Custom) {
+	for _, c := range custom.Checks {
+		if c.Address != "" {
+			r.customTCP(custom.Group, c)
+			continue
+		}
+		r.customHTTP(custom.Group, c)
+	}
+}
+
+func (r *Runner) customTCP(group string, c endpoints.CustomCheck) {
+	ctx, cancel := context.WithTimeout(r.ctx, c.Timeout)
+	defer cancel()
+	start := time.Now()
+	conn, err := r.tcpDialer(c.Timeout)(ctx, c.Address)
+	res := Result{Group: group, Check: "tcp", Endpoint: c.Address, Detail: c.Name, Duration: time.Since(start)}
+	if err != nil {
+		r.printf("%s %s: TCP connection to %s%s failed: %v\n", markFail, c.Name, c.Address, r.remapNote(c.Address), err)
+		res.fail(err)
+		r.record(res)
+		return
+	}
+	conn.Close()
+	r.printf("%s %s: TCP connection to %s%s\n", markOK, c.Name, c.Address, r.remapNote(c.Address))
+	res.Outcome = Reachable
+	r.record(res)
+}
+
+func (r *Runner) customHTTP(group string, c endpoints.CustomCheck) {
+	res := Result{Group: group, Check: "http", Endpoint: c.URL, Detail: c.Name}
+	ctx, cancel := context.WithTimeout(r.ctx, c.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, c.Method, c.URL, strings.NewReader(c.Body))
+	if err != nil {
+		r.printf("%s %s: %v\n", markFail, c.Name, err)
+		res.fail(err)
+		r.record(res)
+		return
+	}
+	for k, v := range c.Headers {
+		req.Header.Set(k, v)
+	}
+	// Go sends req.Host, not a Host header
+	if host := req.Header.Get("Host"); host != "" {
+		req.Host = host
+	}
+
+	log.Debugf("Sending %s req to %s", c.Method, c.URL)
+	start := time.Now()
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		res.Duration = time.Since(start)
+		r.printf("%s %s: %s %s%s not reachable: %v\n", markFail, c.Name, c.Method, c.URL, r.remapNote(c.URL), errString(err))
+		res.fail(err)
+		r.record(res)
+		return
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	res.Duration = time.Since(start)
+	res.fromResponse(resp)
+	if err == nil {
+		err = assertResponse(c, res, body)
+	}
+	log.WithFields(log.Fields{
+		"status": resp.Status,
+		"resp":   resp,
+	}).Debugf("%s %s answered", c.Method, c.URL)
+	if err != nil {
+		r.printf("%s %s: %s %s%s returned %s, %v\n", markFail, c.Name, c.Method, c.URL, r.remapNote(c.URL), resp.Status, err)
+		res.fail(err)
+		r.record(res)
+		return
+	}
+	r.printf("%s %s: %s %s%s returned %s\n", markOK, c.Name, c.Method, c.URL, r.remapNote(c.URL), resp.Status)
+	res.Outcome = Reachable
+	r.record(res)
+}
+
+// assertResponse checks the status, body regex and JSON path of a custom
+// check.  res carries what fromResponse found.
+func assertResponse(c endpoints.CustomCheck, res Result, body []byte) error {
+	if res.BlockPage {
+		return fmt.Errorf("a security product answered with its block page")
+	}
+	if res.OffSite != "" {
+		return fmt.Errorf("redirected to %s", res.OffSite)
+	}
+	if !c.Passes(res.StatusCode) {
+		return fmt.Errorf("expected status %s", joinInts(c.Status))
+	}
+	if re := c.BodyPattern(); re != nil && !re.Match(body) {
+		return fmt.Errorf("body does not match %s", re)
+	}
+	if c.JSONPath == "" {
+		return nil
+	}
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return fmt.Errorf("body is not JSON: %v", err)
+	}
+	value, ok := jsonPath(doc, c.JSONPath)
+	if !ok {
+		return fmt.Errorf("JSON has no %s", c.JSONPath)
+	}
+	if c.JSONEquals != "" && fmt.Sprint(value) != c.JSONEquals {
+		return fmt.Errorf("JSON %s is %v, expected %s", c.JSONPath, value, c.JSONEquals)
+	}
+	return nil
+}
+
+// jsonPath walks a dotted path of object keys and array indexes
+func jsonPath(doc interface{}, path string) (interface{}, bool) {
+	for _, key := range strings.Split(path, ".") {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			v, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			doc = v
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			doc = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return doc, true
+}
+
+func joinInts(list []int) string {
+	s := make([]string, len(list))
+	for i, n := range list {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, " or ")
+}
@@ -0,0 +1,78 @@
+package connections
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mdsauce/nethelp/endpoints"
+)
+
+func TestJSONPath(t *testing.T) {
+	var doc interface{}
+	raw := `{"value": {"ready": true, "build": {"version": "4.2"}}, "items": [{"id": 7}, {"id": "x"}], "empty": null}`
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path   string
+		want   interface{}
+		wantOK bool
+	}{
+		{"value.ready", true, true},
+		{"value.build.version", "4.2", true},
+		{"items.0.id", float64(7), true},
+		{"items.1.id", "x", true},
+		{"empty", nil, true},
+		{"items.2.id", nil, false},
+		{"items.-1", nil, false},
+		{"items.first", nil, false},
+		{"value.missing", nil, false},
+		{"value.ready.more", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, ok := jsonPath(doc, tt.path)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("jsonPath(%q) = %v, %v, want %v, %v", tt.path, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestAssertResponse(t *testing.T) {
+	custom := endpoints.Custom{Checks: []endpoints.CustomCheck{
+		{URL: "https://staging.acme.com/health"},
+		{URL: "https://staging.acme.com/health", Status: []int{200, 204}, BodyRegex: `"status":\s*"ok"`},
+		{URL: "https://staging.acme.com/health", JSONPath: "value.ready", JSONEquals: "true"},
+	}}
+	if err := custom.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	plain, regex, path := custom.Checks[0], custom.Checks[1], custom.Checks[2]
+	tests := []struct {
+		name    string
+		check   endpoints.CustomCheck
+		res     Result
+		body    string
+		wantErr bool
+	}{
+		{"default status", plain, Result{StatusCode: 200}, "", false},
+		{"unexpected status", plain, Result{StatusCode: 503}, "", true},
+		{"block page", plain, Result{StatusCode: 200, BlockPage: true}, "", true},
+		{"redirected away", plain, Result{StatusCode: 200, OffSite: "https://login.hotel.example/"}, "", true},
+		{"listed status and regex", regex, Result{StatusCode: 204}, `{"status": "ok"}`, false},
+		{"regex does not match", regex, Result{StatusCode: 200}, `{"status": "down"}`, true},
+		{"json equals", path, Result{StatusCode: 200}, `{"value": {"ready": true}}`, false},
+		{"json differs", path, Result{StatusCode: 200}, `{"value": {"ready": false}}`, true},
+		{"json path missing", path, Result{StatusCode: 200}, `{"value": {}}`, true},
+		{"not json", path, Result{StatusCode: 200}, `<html>`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := assertResponse(tt.check, tt.res, []byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("assertResponse() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package connections
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Payload is a download of known size and SHA-256 for the integrity check
+type Payload struct {
+	URL    string
+	Size   int64
+	SHA256 string
+}
+
+// IntegrityCheck downloads the payload once uncompressed and once with
+// gzip, decoding the body itself, and checks length, checksum and the
+// Content-Encoding of each.  Proxies that buffer large bodies cut them
+// off, and some decompress or re-encode them on the way.
+func (r *Runner) IntegrityCheck(p Payload) {
+	for _, encoding := range []string{"identity", "gzip"} {
+		res := Result{Group: "network", Check: "integrity", Endpoint: p.URL, Detail: encoding}
+		start := time.Now()
+		note, err := r.download(p, encoding, &res)
+		res.Duration = time.Since(start)
+		if err != nil {
+			res.fail(err)
+			r.printf("%s %s with %s: %s\n", markFail, p.URL, encoding, res.Error)
+			r.record(res)
+			continue
+		}
+		res.Outcome = Reachable
+		r.printf("%s %s with %s: %d bytes intact%s\n", markOK, p.URL, encoding, p.Size, note)
+		r.record(res)
+	}
+}
+
+// download fetches the payload with one Accept-Encoding and verifies it
+func (r *Runner) download(p Payload, encoding string, res *Result) (string, error) {
+	req, err := http.NewRequestWithContext(r.ctx, "GET", p.URL, nil)
+	if err != nil {
+		return "", err
+	}
+	// set by hand, so the transport hands over the body as it arrived
+	req.Header.Set("Accept-Encoding", encoding)
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	res.fromResponse(resp)
+	raw, err := ioutil.ReadAll(io.LimitReader(resp.Body, p.Size+1<<20))
+	if err != nil {
+		return "", withCause(CauseIntegrity, fmt.Errorf("body cut off after %d bytes: %v", len(raw), err))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("returned %s", resp.Status)
+	}
+	if resp.ContentLength >= 0 && resp.ContentLength != int64(len(raw)) {
+		return "", withCause(CauseIntegrity, fmt.Errorf("received %d bytes of the %d the Content-Length announced", len(raw), resp.ContentLength))
+	}
+
+	var note string
+	body := raw
+	gzipped := bytes.HasPrefix(raw, []byte{0x1f, 0x8b})
+	switch enc := resp.Header.Get("Content-Encoding"); {
+	case enc == "gzip":
+		zr, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return "", withCause(CauseIntegrity, errors.New("the Content-Encoding is gzip but the body is not"))
+		}
+		if body, err = ioutil.ReadAll(zr); err != nil {
+			return "", withCause(CauseIntegrity, fmt.Errorf("the Content-Encoding is gzip but the body does not decompress: %v", err))
+		}
+		if encoding == "identity" {
+			note = ", gzipped although it was not asked for"
+		} else {
+			note = fmt.Sprintf(", %d bytes on the wire", len(raw))
+		}
+	case enc != "" && enc != "identity":
+		return "", withCause(CauseIntegrity, fmt.Errorf("unexpected Content-Encoding %s", enc))
+	case gzipped:
+		return "", withCause(CauseIntegrity, errors.New("the body is gzip but the Content-Encoding header is missing"))
+	case encoding == "gzip":
+		note = ", sent uncompressed"
+	}
+	log.WithFields(log.Fields{
+		"encoding": resp.Header.Get("Content-Encoding"),
+		"wire":     len(raw),
+		"decoded":  len(body),
+	}).Debugf("Downloaded %s", p.URL)
+
+	if int64(len(body)) != p.Size {
+		return "", withCause(CauseIntegrity, fmt.Errorf("received %d bytes of %d", len(body), p.Size))
+	}
+	sum := sha256.Sum256(body)
+	if p.SHA256 != "" && hex.EncodeToString(sum[:]) != p.SHA256 {
+		return "", withCause(CauseIntegrity, errors.New("checksum mismatch, the body was changed on the way"))
+	}
+	return note, nil
+}
@@ -0,0 +1,39 @@
+package connections
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+)
+
+// Timing is one connect and TLS handshake
+type Timing struct {
+	Connect time.Duration
+	TLS     time.Duration
+	Err     error
+}
+
+// Handshake opens a TCP connection to addr, through the proxy of the run
+// if any, and does a TLS handshake on it.  It only measures, so the
+// certificate is not verified.
+func (r *Runner) Handshake(addr string) Timing {
+	var t Timing
+	ctx, cancel := context.WithTimeout(r.ctx, 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	conn, err := r.tcpDialer(5*time.Second)(ctx, addr)
+	t.Connect = time.Since(start)
+	if err != nil {
+		t.Err = err
+		return t
+	}
+	defer conn.Close()
+	host, _, _ := net.SplitHostPort(addr)
+	tlsConn := tls.Client(conn, &tls.Config{ServerName: host, InsecureSkipVerify: true})
+	tlsConn.SetDeadline(time.Now().Add(5 * time.Second))
+	start = time.Now()
+	t.Err = tlsConn.Handshake()
+	t.TLS = time.Since(start)
+	return t
+}
@@ -0,0 +1,153 @@
+package connections
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mdsauce/nethelp/echo"
+	log "github.com/sirupsen/logrus"
+)
+
+// MatrixSession is the made up session the method matrix sends its
+// commands to.  Every hub answers them with a WebDriver error.
+const MatrixSession = "nethelp-method-matrix"
+
+// matrixCase is one request of the method matrix
+type matrixCase struct {
+	method string
+	body   bool
+	expect bool
+}
+
+func (c matrixCase) String() string {
+	s := c.method
+	if c.body {
+		s += " with body"
+	}
+	if c.expect {
+		s += ", Expect: 100-continue"
+	}
+	return s
+}
+
+// matrix holds the requests Selenium sends and proxies get wrong: GET
+// works while POST and DELETE are blocked, bodies are cut or 100-continue hangs
+var matrix = []matrixCase{
+	{"GET", false, false},
+	{"POST", false, false},
+	{"POST", true, false},
+	{"POST", true, true},
+	{"PUT", false, false},
+	{"PUT", true, false},
+	{"PUT", true, true},
+	{"DELETE", false, false},
+	{"DELETE", true, false},
+}
+
+// matrixBody is large enough that a proxy has to stream it
+var matrixBody = []byte(`{"nethelp":"method matrix","padding":"` + strings.Repeat("x", 16<<10) + `"}`)
+
+// MethodMatrix sends every request of the matrix to a WebDriver hub.  A
+// request passes when the hub answers with JSON, which it does even for
+// errors; anything else came from something in between.
+func (r *Runner) MethodMatrix(group, hub string) {
+	target := strings.TrimSuffix(hub, "/") + "/session/" + MatrixSession
+	r.methodMatrix(group, target, func(c matrixCase, body []byte) (string, error) {
+		if !json.Valid(body) {
+			return "", fmt.Errorf("answered without WebDriver JSON")
+		}
+		return "", nil
+	})
+}
+
+// EchoMatrix sends every request of the matrix to an echo target, the
+// mock or the idle server, and also proves the method and the body
+// arrived unchanged
+func (r *Runner) EchoMatrix(target string) {
+	r.methodMatrix("network", target, func(c matrixCase, body []byte) (string, error) {
+		var reply echo.Reply
+		if err := json.Unmarshal(body, &reply); err != nil {
+			return "", fmt.Errorf("answered without echo JSON")
+		}
+		var sent []byte
+		if c.body {
+			sent = matrixBody
+		}
+		switch {
+		case reply.Method != c.method:
+			return "", fmt.Errorf("arrived as %s", reply.Method)
+		case reply.Length != int64(len(sent)):
+			return "", fmt.Errorf("body arrived with %d of %d bytes", reply.Length, len(sent))
+		case reply.SHA256 != echo.Sum(sent):
+			return "", fmt.Errorf("body was changed on the way")
+		case c.expect && reply.Expect == "":
+			return "Expect header removed on the way", nil
+		}
+		return "", nil
+	})
+}
+
+// methodMatrix sends the matrix to target and records one result per
+// request.  verify judges an answer that is not a transport error.
+func (r *Runner) methodMatrix(group, target string, verify func(matrixCase, []byte) (string, error)) {
+	r.printf("Method matrix against %s%s\n", target, r.remapNote(target))
+	for _, c := range matrix {
+		var body io.Reader
+		if c.body {
+			body = bytes.NewReader(matrixBody)
+		}
+		req, err := http.NewRequestWithContext(r.ctx, c.method, target, body)
+		if err != nil {
+			r.printf("    %s %s: %v\n", markFail, c, err)
+			continue
+		}
+		if c.body {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if c.expect {
+			req.Header.Set("Expect", "100-continue")
+		}
+		res := Result{Group: group, Check: "method", Endpoint: target, Detail: c.String()}
+		start := time.Now()
+		resp, err := r.Client.Do(req)
+		if err != nil {
+			res.Duration = time.Since(start)
+			res.fail(err)
+			r.printf("    %s %s: %s\n", markFail, c, res.Error)
+			r.record(res)
+			continue
+		}
+		answer, _ := ioutil.ReadAll(io.LimitReader(resp.Body, peekSize))
+		resp.Body.Close()
+		res.Duration = time.Since(start)
+		res.fromResponse(resp)
+		log.WithFields(log.Fields{
+			"status":       resp.Status,
+			"content-type": resp.Header.Get("Content-Type"),
+		}).Debugf("%s %s answered", c, target)
+
+		note, err := verify(c, answer)
+		if err == nil && (res.BlockPage || res.OffSite != "") {
+			err = fmt.Errorf("answered by something in between")
+		}
+		if err != nil {
+			// the answer came back, so whatever is wrong with it happened on the way
+			res.fail(withCause(CauseProxyBlock, err))
+			r.printf("    %s %s: %s, %s\n", markFail, c, resp.Status, err)
+			r.record(res)
+			continue
+		}
+		res.Outcome = Reachable
+		if note != "" {
+			note = ", " + note
+		}
+		r.printf("    %s %s: %s%s\n", markOK, c, resp.Status, note)
+		r.record(res)
+	}
+}
@@ -3,27 +3,36 @@
 package connections
 
 import (
-	"fmt"
 	"net/http"
 
 	log "github.com/sirupsen/logrus"
 )
 
-func respOutput(resp *http.Response, endpoint string) {
-	if resp.StatusCode == 200 {
-		fmt.Printf("[OK] %s is reachable %s\n", endpoint, resp.Status)
+// Markers printed in front of every result
+const (
+	markOK   = "[OK]"
+	markFail = "[ERROR]"
+	markAuth = "[AUTH]"
+)
+
+// respOutput prints the verdict of a plain request.  res carries what
+// fromResponse found, a block page or a redirect to another site is no success.
+func (r *Runner) respOutput(resp *http.Response, endpoint string, res Result) {
+	endpoint += r.remapNote(endpoint)
+	if resp.StatusCode == 200 && !res.BlockPage && res.OffSite == "" {
+		r.printf("[OK] %s is reachable %s\n", endpoint, resp.Status)
 		log.WithFields(log.Fields{
 			"status": resp.Status,
 			"resp":   resp,
 		}).Infof("[OK] %s reachable.\n", endpoint)
 	} else if resp.StatusCode == 401 {
-		fmt.Printf("[OK] %s is reachable but returned %s\n", endpoint, resp.Status)
+		r.printf("[OK] %s is reachable but returned %s\n", endpoint, resp.Status)
 		log.WithFields(log.Fields{
 			"status": resp.Status,
 			"resp":   resp,
 		}).Infof("[OK] %s reachable but unauthenticated.\n", endpoint)
 	} else {
-		fmt.Printf("[ERROR] %s returned %s\n", endpoint, resp.Status)
+		r.printf("[ERROR] %s returned %s\n", endpoint, resp.Status)
 		log.WithFields(log.Fields{
 			"status": resp.Status,
 			"resp":   resp,
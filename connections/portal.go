@@ -0,0 +1,146 @@
+package connections
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/publicsuffix"
+)
+
+// DefaultPortalProbe answers 204 No Content with an empty body on the open
+// internet.  A captive portal answers it with a redirect or a login page.
+const DefaultPortalProbe = "http://connectivitycheck.gstatic.com/generate_204"
+
+// CaptivePortal sends the no-content probe without following redirects and
+// tells whether a captive portal answered it: a redirect to another site or
+// a web page that is not a block page.  A block page or an error status
+// only means the probe is blocked, that is recorded and the checks run.
+// A probe that cannot connect proves nothing either way and is not recorded.
+func (r *Runner) CaptivePortal(probe string) bool {
+	client := *r.Client
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	req, err := http.NewRequestWithContext(r.ctx, "GET", probe, nil)
+	if err != nil {
+		log.Warn("The captive portal probe is not valid. ", err)
+		return false
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		log.WithField("error", err).Debug("The captive portal probe could not connect.")
+		return false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusProxyAuthRequired {
+		// the proxy itself turned the probe away, the checks report it
+		log.Debug("The captive portal probe needs proxy credentials.")
+		return false
+	}
+
+	res := Result{Group: "network", Check: "captive-portal", Endpoint: probe, Outcome: Reachable, Duration: time.Since(start)}
+	res.fromResponse(resp)
+	var portal string
+	switch {
+	case res.BlockPage || resp.StatusCode >= 400:
+		res.Outcome = Failed
+		res.Error = "probe blocked, answered " + resp.Status
+		if res.BlockPage {
+			res.Error = "probe blocked by a block page from " + res.Vendor
+		}
+		r.printf("%s The captive portal probe %s was blocked (%s), the checks run anyway.\n", markFail, probe, resp.Status)
+		r.record(res)
+		return false
+	case resp.StatusCode >= 300 && resp.StatusCode < 400:
+		if loc, err := resp.Location(); err == nil && offSite(probe, []string{loc.String()}) != "" {
+			portal = loc.String()
+			res.OffSite = portal
+		}
+	case resp.StatusCode == http.StatusOK && isHTML(resp):
+		portal = "a login page"
+	}
+	if portal == "" {
+		log.Debug("No captive portal, the probe answered ", resp.Status)
+		r.record(res)
+		return false
+	}
+	res.Outcome = Failed
+	res.Error = "captive portal answered " + resp.Status
+	res.Cause = CauseCaptive
+	if res.OffSite != "" {
+		r.printf("%s You are behind a captive portal, %s was redirected to %s.\n", markFail, probe, res.OffSite)
+	} else {
+		r.printf("%s You are behind a captive portal, %s was answered with a web page.\n", markFail, probe)
+	}
+	r.printf("    Log in or accept the terms in a browser on this machine, then run nethelp again.\n")
+	r.record(res)
+	return true
+}
+
+// isHTML tells whether resp carries a web page
+func isHTML(resp *http.Response) bool {
+	if strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "text/html") {
+		return true
+	}
+	var head []byte
+	if body, ok := resp.Body.(*peekedBody); ok {
+		head = body.Head()
+	}
+	return strings.Contains(http.DetectContentType(head), "text/html")
+}
+
+// redirectChain lists every URL the client requested for resp, starting
+// with the first.  Each redirected request points back at the response that caused it.
+func redirectChain(resp *http.Response) []string {
+	var chain []string
+	for req := resp.Request; req != nil; req = req.Response.Request {
+		chain = append([]string{req.URL.String()}, chain...)
+		if req.Response == nil {
+			break
+		}
+	}
+	return chain
+}
+
+// offSite returns the first hop of the chain that left the site of endpoint,
+// e.g. a redirect from ondemand.saucelabs.com to a hotel login page
+func offSite(endpoint string, chain []string) string {
+	from, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
+	}
+	for _, hop := range chain {
+		to, err := url.Parse(hop)
+		if err == nil && Site(to.Hostname()) != Site(from.Hostname()) {
+			return hop
+		}
+	}
+	return ""
+}
+
+// Site cuts a host down to its registrable domain, api.us-west-1.saucelabs.com
+// and saucelabs.com are the same site, app.acme.co.uk and login.other.co.uk
+// are not.  IP addresses and hosts without a public suffix are their own site.
+func Site(host string) string {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	if s, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return s
+	}
+	return host
+}
+
+// redirectNote explains a check that was redirected to another site
+func (r *Runner) redirectNote(res Result) {
+	if res.OffSite == "" || res.Check == "captive-portal" {
+		return
+	}
+	r.printf("    %s redirected to %s after %d hop(s)\n", markFail, res.OffSite, len(res.Redirects))
+}
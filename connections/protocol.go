@@ -0,0 +1,100 @@
+package connections
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ProtocolCheck sums up the HTTP versions the HTTPS checks negotiated.
+// Through a proxy that never got HTTP/2 it also asks the first HTTPS host
+// directly, to tell a proxy that downgrades HTTP/2 from a server without it.
+func (r *Runner) ProtocolCheck() {
+	r.mu.Lock()
+	counts := make(map[string]int)
+	var sample string
+	for _, res := range r.results {
+		if !strings.HasPrefix(res.Endpoint, "https://") || res.Proto == "" {
+			continue
+		}
+		counts[describeProto(res)]++
+		if sample == "" {
+			sample = res.Endpoint
+		}
+	}
+	r.mu.Unlock()
+	if len(counts) == 0 {
+		return
+	}
+	var seen []string
+	for proto, n := range counts {
+		seen = append(seen, fmt.Sprintf("%s on %d check(s)", proto, n))
+	}
+	sort.Strings(seen)
+	r.printf("HTTPS checks negotiated %s\n", strings.Join(seen, ", "))
+
+	if r.Proxy == nil || r.HTTPVersion == "1.1" || counts["HTTP/2.0 (h2)"] > 0 {
+		return
+	}
+	u, err := url.Parse(sample)
+	if err != nil {
+		return
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "443")
+	}
+	res := Result{Group: "network", Check: "http2", Endpoint: "https://" + host, Outcome: Reachable, ProxyScheme: "direct"}
+	start := time.Now()
+	alpn, err := r.directALPN(u.Hostname(), host)
+	res.Duration = time.Since(start)
+	switch {
+	case err != nil:
+		// no direct route, nothing to compare with
+		return
+	case alpn == "h2":
+		res.Outcome = Failed
+		res.Error = "the proxy downgrades HTTP/2 to HTTP/1.1"
+		res.Detail = "direct ALPN h2, through the proxy HTTP/1.1"
+		r.printf("%s %s negotiates HTTP/2 directly but HTTP/1.1 through the proxy, the proxy downgrades HTTP/2.\n", markFail, host)
+	default:
+		res.Detail = "direct ALPN " + orNoALPN(alpn)
+	}
+	res.ALPN = alpn
+	r.record(res)
+}
+
+// directALPN does a TLS handshake with host, bypassing the proxy, and
+// returns the protocol the server picked
+func (r *Runner) directALPN(serverName, addr string) (string, error) {
+	ctx, cancel := context.WithTimeout(r.ctx, 5*time.Second)
+	defer cancel()
+	conn, err := r.Remap.DialContext(&net.Dialer{})(ctx, "tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	tlsConn := tls.Client(conn, &tls.Config{ServerName: serverName, NextProtos: []string{"h2", "http/1.1"}})
+	tlsConn.SetDeadline(time.Now().Add(5 * time.Second))
+	if err := tlsConn.Handshake(); err != nil {
+		return "", err
+	}
+	return tlsConn.ConnectionState().NegotiatedProtocol, nil
+}
+
+// describeProto names the HTTP version and the ALPN protocol behind it
+func describeProto(r Result) string {
+	return fmt.Sprintf("%s (%s)", r.Proto, orNoALPN(r.ALPN))
+}
+
+func orNoALPN(s string) string {
+	if s == "" {
+		return "no ALPN"
+	}
+	return s
+}
@@ -1,26 +1,29 @@
 package connections
 
 import (
-	"fmt"
-	"net/http"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
 // PublicSites attempts to prove that the machine has internet
 // connectivity and is not being blocked by a private network.
-func PublicSites(sitelist []string) {
+func (r *Runner) PublicSites(sitelist []string) {
 	for _, site := range sitelist {
 		log.Debug("Sending GET req to ", site)
-		resp, err := http.Get(site)
+		start := time.Now()
+		resp, err := r.get(site)
 		if err != nil {
-			fmt.Printf("[ ] %s not reachable\n", site)
+			r.printf("[ ] %s not reachable%s\n", site, r.remapNote(site))
 			log.WithFields(log.Fields{
 				"error": err,
 			}).Infof("[ ] %s not reachable\n", site)
 		}
+		res := httpResult("public", site, start, resp, err)
 		if err == nil {
-			respOutput(resp, site)
+			r.respOutput(resp, site, res)
+			resp.Body.Close()
 		}
+		r.record(res)
 	}
 }
@@ -1,33 +1,114 @@
 package connections
 
 import (
-	"bytes"
-	"fmt"
+	"encoding/json"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// hubStatus is the WebDriver /status payload.  Appium and W3C hubs
+// report ready, older JSON wire protocol hubs only report the build.
+type hubStatus struct {
+	Value *struct {
+		Ready   *bool  `json:"ready"`
+		Message string `json:"message"`
+		Build   struct {
+			Version  string `json:"version"`
+			Revision string `json:"revision"`
+		} `json:"build"`
+	} `json:"value"`
+}
+
 // RDCServices makes connections to the main RDC endpoints to prove
-// that the endpoints are reachable from the machine
-func RDCServices(rdcEndpoints []string) {
+// that the endpoints are reachable from the machine.  A 200 from a
+// /status endpoint must also carry a WebDriver status payload, so a
+// block page answering in place of the hub is not mistaken for success.
+func (r *Runner) RDCServices(rdcEndpoints []string) {
 	for _, endpoint := range rdcEndpoints {
-		log.Debug("Sending req to ", endpoint)
-		var jsonBody = []byte(`{"test":"this will result in an HTTP 500 resp or 401 resp."}`)
-		req, err := http.NewRequest("GET", endpoint, bytes.NewBuffer(jsonBody))
-		req.Header.Set("Content-Type", "application/json")
-
-		client := &http.Client{}
-		resp, err := client.Do(req)
+		log.Debug("Sending GET req to ", endpoint)
+		start := time.Now()
+		resp, err := r.get(endpoint)
 		if err != nil {
-			fmt.Printf("[ ] %s not reachable\n", endpoint)
+			r.printf("[ ] %s not reachable%s\n", endpoint, r.remapNote(endpoint))
 			log.WithFields(log.Fields{
 				"error": err,
 			}).Infof("[ ] %s not reachable\n", endpoint)
+			r.record(httpResult("rdc", endpoint, start, resp, err))
+			continue
+		}
+
+		// the requested path decides, a redirect must not skip the validation
+		if resp.StatusCode != http.StatusOK || !isStatus(endpoint) {
+			res := httpResult("rdc", endpoint, start, resp, err)
+			r.respOutput(resp, endpoint, res)
+			resp.Body.Close()
+			r.record(res)
+			continue
 		}
+		outcome, detail, cause := r.statusOutput(resp, endpoint)
+		res := Result{Group: "rdc", Check: "http", Endpoint: endpoint, Outcome: outcome, Detail: detail, Cause: cause, Duration: time.Since(start)}
+		res.fromResponse(resp)
+		r.record(res)
+	}
+}
+
+// isStatus tells whether endpoint is the /status of a hub
+func isStatus(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	return err == nil && strings.HasSuffix(u.Path, "/status")
+}
+
+// statusOutput checks that a 200 from a hub /status endpoint is WebDriver
+// JSON and that the hub is ready.  Returns the verdict, the build info and
+// the cause of a failure it knows.
+func (r *Runner) statusOutput(resp *http.Response, endpoint string) (Outcome, string, Cause) {
+	defer resp.Body.Close()
+	shown := endpoint + r.remapNote(endpoint)
+
+	if resp.Request.Response != nil {
+		// a hub answers /status itself, a redirect leads to a login or block page
+		r.printf("%s %s was redirected to %s instead of answering as a WebDriver hub\n", markFail, shown, resp.Request.URL)
+		return Failed, "redirected to " + resp.Request.URL.String() + ", response is not WebDriver status JSON", CauseProxyBlock
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		r.printf("%s %s returned %s but the body could not be read: %v\n", markFail, shown, resp.Status, err)
+		return Failed, err.Error(), ""
+	}
+	var status hubStatus
+	if err := json.Unmarshal(body, &status); err != nil || status.Value == nil {
+		return Failed, r.notJSON(shown, "WebDriver status JSON", resp, body, err), CauseProxyBlock
+	}
 
-		if err == nil {
-			respOutput(resp, endpoint)
+	detail := "build unknown"
+	if status.Value.Build.Version != "" {
+		detail = "build " + status.Value.Build.Version
+		if status.Value.Build.Revision != "" {
+			detail += " (" + status.Value.Build.Revision + ")"
 		}
 	}
+	if status.Value.Ready != nil && !*status.Value.Ready {
+		r.printf("%s %s is reachable but the hub is not ready: %s\n", markFail, shown, status.Value.Message)
+		log.WithFields(log.Fields{
+			"status":  resp.Status,
+			"message": status.Value.Message,
+			"build":   status.Value.Build,
+		}).Infof("%s %s not ready.\n", markFail, endpoint)
+		return Failed, "hub not ready, " + detail, ""
+	}
+	if status.Value.Ready == nil {
+		detail += ", no ready field"
+	}
+	r.printf("%s %s is reachable %s, %s\n", markOK, shown, resp.Status, detail)
+	log.WithFields(log.Fields{
+		"status":  resp.Status,
+		"message": status.Value.Message,
+		"build":   status.Value.Build,
+	}).Infof("%s %s reachable and ready.\n", markOK, endpoint)
+	return Reachable, detail, ""
 }
@@ -0,0 +1,32 @@
+package connections
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// remapNote marks endpoints that were dialed at an address set by
+// --resolve or --connect-to instead of the one DNS would return
+func (r *Runner) remapNote(endpoint string) string {
+	hostport := endpoint
+	if strings.Contains(endpoint, "://") {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return ""
+		}
+		port := u.Port()
+		if port == "" {
+			port = "80"
+			if u.Scheme == "https" {
+				port = "443"
+			}
+		}
+		hostport = net.JoinHostPort(u.Hostname(), port)
+	}
+	if to, ok := r.Remap.Applied(hostport); ok {
+		return fmt.Sprintf(" (remapped to %s)", to)
+	}
+	return ""
+}
@@ -0,0 +1,184 @@
+package connections
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mdsauce/nethelp/endpoints"
+	"github.com/mdsauce/nethelp/fingerprint"
+	"github.com/mdsauce/nethelp/proxy"
+)
+
+// Outcome is the verdict of one check
+type Outcome string
+
+// Every check ends in one of these
+const (
+	Reachable    Outcome = "reachable"
+	Unauthorized Outcome = "unauthorized"
+	Failed       Outcome = "failed"
+)
+
+// Result is the structured record of one check, kept next to the
+// terminal output so runs can be saved, bundled and reported on
+type Result struct {
+	Time       time.Time     `json:"time"`
+	Group      string        `json:"group"`
+	Check      string        `json:"check"`
+	Datacenter string        `json:"datacenter,omitempty"`
+	Endpoint   string        `json:"endpoint"`
+	Outcome    Outcome       `json:"outcome"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Status     string        `json:"status,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Detail     string        `json:"detail,omitempty"`
+	Duration   time.Duration `json:"duration_ns"`
+	RemappedTo string        `json:"remapped_to,omitempty"`
+	TLSVersion string        `json:"tls_version,omitempty"`
+	TLSIssuer  string        `json:"tls_issuer,omitempty"`
+	Proto      string        `json:"proto,omitempty"`
+	ALPN       string        `json:"alpn,omitempty"`
+	Cause      Cause         `json:"cause,omitempty"`
+	Vendor     string        `json:"vendor,omitempty"`
+	BlockPage  bool          `json:"block_page,omitempty"`
+	Evidence   string        `json:"evidence,omitempty"`
+	Redirects  []string      `json:"redirects,omitempty"`
+	OffSite    string        `json:"off_site,omitempty"`
+	ServerDate string        `json:"server_date,omitempty"`
+	ClockSkew  time.Duration `json:"clock_skew_ns,omitempty"`
+	CertWindow string        `json:"cert_window,omitempty"`
+	// ProxyScheme and RemoteDNS tell how the check reached the network,
+	// empty when it connected directly
+	ProxyScheme string `json:"proxy_scheme,omitempty"`
+	RemoteDNS   bool   `json:"remote_dns,omitempty"`
+
+	// err is the error the check failed with, Classify sorts it by type
+	err error
+}
+
+// fail marks the result failed with err
+func (r *Result) fail(err error) {
+	r.Outcome = Failed
+	r.Error = errString(err)
+	r.err = err
+}
+
+// fromResponse copies the status and TLS details of resp into the result
+func (r *Result) fromResponse(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+	r.StatusCode = resp.StatusCode
+	r.Status = resp.Status
+	r.Proto = resp.Proto
+	if resp.TLS != nil {
+		r.TLSVersion = tlsVersions[resp.TLS.Version]
+		r.ALPN = resp.TLS.NegotiatedProtocol
+		if len(resp.TLS.PeerCertificates) > 0 {
+			r.TLSIssuer = resp.TLS.PeerCertificates[0].Issuer.String()
+		}
+	}
+	r.clockFromResponse(resp)
+	if chain := redirectChain(resp); len(chain) > 1 {
+		r.Redirects = chain[1:]
+		r.OffSite = offSite(chain[0], r.Redirects)
+	}
+	var head []byte
+	if body, ok := resp.Body.(*peekedBody); ok {
+		head = body.Head()
+	}
+	if m, ok := fingerprint.Identify(resp, head, r.TLSIssuer); ok {
+		r.Vendor = m.Vendor
+		r.BlockPage = m.BlockPage
+		r.Evidence = m.Evidence
+	}
+}
+
+var tlsVersions = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+// record fills in the fields every result shares and stores it
+func (r *Runner) record(res Result) Result {
+	if res.Time.IsZero() {
+		res.Time = time.Now()
+	}
+	if res.Datacenter == "" {
+		res.Datacenter = endpoints.RegionOf(res.Endpoint)
+	}
+	if res.ProxyScheme == "" && r.Proxy != nil {
+		res.ProxyScheme = r.Proxy.Scheme
+		res.RemoteDNS = proxy.RemoteDNS(r.Proxy)
+	}
+	if res.BlockPage && res.Outcome == Reachable {
+		res.Outcome = Failed
+		res.Error = "block page from " + res.Vendor
+	}
+	if res.OffSite != "" && res.Check != "captive-portal" {
+		res.Outcome = Failed
+		if res.Error == "" {
+			res.Error = "redirected to " + res.OffSite
+		}
+	}
+	res.Cause = Classify(res)
+	r.vendorNote(res)
+	r.redirectNote(res)
+	if note := r.remapNote(res.Endpoint); note != "" {
+		res.RemappedTo = strings.TrimSuffix(strings.TrimPrefix(note, " (remapped to "), ")")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results = append(r.results, res)
+	return res
+}
+
+// vendorNote names the product behind a block page, and every product
+// the traffic passes through the first time it shows up
+func (r *Runner) vendorNote(res Result) {
+	if res.Vendor == "" {
+		return
+	}
+	r.mu.Lock()
+	seen := r.vendors[res.Vendor]
+	if r.vendors == nil {
+		r.vendors = make(map[string]bool)
+	}
+	r.vendors[res.Vendor] = true
+	r.mu.Unlock()
+	switch {
+	case res.BlockPage:
+		r.printf("    %s block page from %s (%s).  Talk to %s.\n", markFail, res.Vendor, res.Evidence, fingerprint.TeamOf(res.Vendor))
+	case !seen:
+		r.printf("    Traffic passes through %s (%s).\n", res.Vendor, res.Evidence)
+	}
+}
+
+// httpResult builds the result of a plain reachability check.
+// A 401 still proves the endpoint is reachable.
+func httpResult(group, endpoint string, start time.Time, resp *http.Response, err error) Result {
+	r := Result{Group: group, Check: "http", Endpoint: endpoint, Duration: time.Since(start)}
+	switch {
+	case err != nil:
+		r.fail(err)
+	case resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusUnauthorized:
+		r.Outcome = Reachable
+	default:
+		r.Outcome = Failed
+	}
+	r.fromResponse(resp)
+	return r
+}
+
+// errString drops the repeated "Get <url>:" prefix of url.Error
+func errString(err error) string {
+	if uerr, ok := err.(*url.Error); ok {
+		return uerr.Err.Error()
+	}
+	return err.Error()
+}
@@ -0,0 +1,154 @@
+package connections
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/mdsauce/nethelp/proxy"
+	"github.com/mdsauce/nethelp/redact"
+	"github.com/mdsauce/nethelp/remap"
+)
+
+// Runner runs the checks of one diagnostic run.  Every run has its own
+// HTTP clients, remapping rules, output and results, so several runs can
+// share a process without touching http.DefaultTransport.
+type Runner struct {
+	// Client sends every HTTP and REST API check
+	Client *http.Client
+	// SessionClient sends WebDriver commands and waits long enough for a VM or device
+	SessionClient *http.Client
+	// Proxy is the proxy TCP checks dial through, if any
+	Proxy *url.URL
+	// Remap holds the --resolve and --connect-to rules of the run, if any
+	Remap *remap.Remapper
+	// HTTPVersion is the HTTP version the run forces, 1.1 or 2, if any
+	HTTPVersion string
+	// Out receives the human readable progress with every secret masked
+	Out io.Writer
+
+	ctx     context.Context
+	results []Result
+	vendors map[string]bool
+	mu      sync.Mutex
+}
+
+// NewRunner builds a Runner whose checks stop when ctx is done.
+// A nil out discards the progress output.
+func NewRunner(ctx context.Context, client *http.Client, out io.Writer) *Runner {
+	if out == nil {
+		out = ioutil.Discard
+	}
+	next := client.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	peeking := *client
+	peeking.Transport = peekTransport{next: next}
+	return &Runner{
+		Client:        &peeking,
+		SessionClient: &http.Client{Transport: peeking.Transport, Timeout: 5 * time.Minute},
+		Out:           out,
+		ctx:           ctx,
+	}
+}
+
+// Results returns every check recorded so far, in the order they ran
+func (r *Runner) Results() []Result {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Result(nil), r.results...)
+}
+
+// PrintRoute says how the checks reach the network, directly or through
+// which kind of proxy, and who resolves the host names
+func (r *Runner) PrintRoute() {
+	if r.Proxy == nil {
+		r.printf("Checks connect directly, host names are resolved locally.\n")
+		return
+	}
+	dns := "host names are resolved locally"
+	if proxy.RemoteDNS(r.Proxy) {
+		dns = "the proxy resolves host names"
+	}
+	r.printf("Checks go through the %s proxy %s, %s.\n", r.Proxy.Scheme, r.Proxy.Host, dns)
+}
+
+// printf writes progress to Out with every secret masked
+func (r *Runner) printf(format string, a ...interface{}) {
+	redact.Fprintf(r.Out, format, a...)
+}
+
+// get sends a plain GET request bound to the run's context
+func (r *Runner) get(endpoint string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(r.ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	return r.Client.Do(req)
+}
+
+// peekSize is how much of every response body is kept for fingerprinting
+const peekSize = 64 << 10
+
+// peekTransport keeps the start of every response body, so a block page
+// is recognized even by checks that never read the body
+type peekTransport struct {
+	next http.RoundTripper
+}
+
+func (t peekTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	resp.Body = &peekedBody{ReadCloser: resp.Body}
+	return resp, nil
+}
+
+// peekedBody keeps the first peekSize bytes of a body as the check reads
+// them.  Head reads ahead only when something fingerprints the body, so
+// checks that never read it do not wait for the download.
+type peekedBody struct {
+	io.ReadCloser
+	head []byte
+	// off is how much of head Read has handed out
+	off int
+	// err ended the body early, Read returns it after head
+	err error
+}
+
+func (b *peekedBody) Read(p []byte) (int, error) {
+	if b.off < len(b.head) {
+		n := copy(p, b.head[b.off:])
+		b.off += n
+		return n, nil
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	n, err := b.ReadCloser.Read(p)
+	if room := peekSize - len(b.head); room > 0 {
+		if room > n {
+			room = n
+		}
+		b.head = append(b.head, p[:room]...)
+		b.off = len(b.head)
+	}
+	b.err = err
+	return n, err
+}
+
+// Head is the start of the body, read ahead up to peekSize
+func (b *peekedBody) Head() []byte {
+	if len(b.head) < peekSize && b.err == nil {
+		more, err := ioutil.ReadAll(io.LimitReader(b.ReadCloser, int64(peekSize-len(b.head))))
+		b.head = append(b.head, more...)
+		b.err = err
+	}
+	return b.head
+}
@@ -0,0 +1,68 @@
+package connections
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// failingBody hands out data, then fails with err
+type failingBody struct {
+	io.Reader
+	err error
+}
+
+func (b *failingBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	if err == io.EOF {
+		return n, b.err
+	}
+	return n, err
+}
+
+func (b *failingBody) Close() error { return nil }
+
+func TestPeekedBody(t *testing.T) {
+	errCut := errors.New("connection reset by peer")
+	long := strings.Repeat("x", peekSize+100)
+	tests := []struct {
+		name     string
+		body     string
+		err      error
+		peek     bool
+		wantHead int
+	}{
+		{"short body read ahead", "<html>blocked</html>", nil, true, 20},
+		{"short body never peeked", "<html>blocked</html>", nil, false, 20},
+		{"long body keeps peekSize", long, nil, true, peekSize},
+		{"cut body read ahead", "partial", errCut, true, 7},
+		{"cut body never peeked", "partial", errCut, false, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var src io.ReadCloser = ioutil.NopCloser(strings.NewReader(tt.body))
+			if tt.err != nil {
+				src = &failingBody{Reader: strings.NewReader(tt.body), err: tt.err}
+			}
+			b := &peekedBody{ReadCloser: src}
+			if tt.peek {
+				if got := len(b.Head()); got != tt.wantHead {
+					t.Fatalf("Head() = %d bytes, want %d", got, tt.wantHead)
+				}
+			}
+			got, err := ioutil.ReadAll(b)
+			if err != tt.err {
+				t.Errorf("ReadAll error = %v, want %v", err, tt.err)
+			}
+			if string(got) != tt.body {
+				t.Errorf("ReadAll = %d bytes, want the %d bytes of the body", len(got), len(tt.body))
+			}
+			if head := b.Head(); len(head) != tt.wantHead || !bytes.HasPrefix([]byte(tt.body), head) {
+				t.Errorf("Head() after reading = %d bytes, want the first %d", len(head), tt.wantHead)
+			}
+		})
+	}
+}
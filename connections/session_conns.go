@@ -0,0 +1,145 @@
+package connections
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mdsauce/nethelp/credentials"
+	log "github.com/sirupsen/logrus"
+)
+
+// sessionCaps are the smallest W3C capabilities that start a session in each cloud
+var sessionCaps = map[string]map[string]interface{}{
+	"vdc": {
+		"browserName":    "chrome",
+		"browserVersion": "latest",
+		"platformName":   "Windows 10",
+		"sauce:options":  map[string]string{"name": "nethelp session test"},
+	},
+	"rdc": {
+		"browserName":           "Chrome",
+		"platformName":          "Android",
+		"appium:deviceName":     "Google.*",
+		"appium:automationName": "UiAutomator2",
+		"sauce:options":         map[string]string{"name": "nethelp session test"},
+	},
+}
+
+// webdriverResp covers both the W3C and the legacy JSON wire protocol responses.
+// Value is only an object for new session and errors.
+type webdriverResp struct {
+	SessionID string          `json:"sessionId"`
+	Value     json.RawMessage `json:"value"`
+}
+
+type webdriverValue struct {
+	SessionID string `json:"sessionId"`
+	Error     string `json:"error"`
+	Message   string `json:"message"`
+}
+
+// SessionTest starts a real WebDriver session on the hub, runs one trivial
+// command and deletes the session again.  This proves a test can start,
+// including proxies that let GET through but block POST bodies or DELETE.
+func (r *Runner) SessionTest(hub, cloud string, creds credentials.Credentials) {
+	caps, ok := sessionCaps[cloud]
+	if !ok {
+		log.Warnf("No session test defined for the %s cloud.", cloud)
+		return
+	}
+	hub = strings.TrimSuffix(hub, "/")
+	r.printf("Session test against %s (%s) as %s\n", hub, creds.Region, creds.Username)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"capabilities": map[string]interface{}{"alwaysMatch": caps},
+	})
+	wd, elapsed, err := r.webdriverCall("POST", hub+"/session", body, creds)
+	if err == nil && wd.SessionID == "" {
+		err = fmt.Errorf("no session ID in the response")
+	}
+	r.recordStep(cloud, hub+"/session", "new session", elapsed, err)
+	if err != nil {
+		r.printf("%s new session failed after %s: %v\n", markFail, elapsed.Round(time.Millisecond), err)
+		return
+	}
+	r.printf("%s new session %s started in %s\n", markOK, wd.SessionID, elapsed.Round(time.Millisecond))
+
+	session := hub + "/session/" + wd.SessionID
+	_, elapsed, err = r.webdriverCall("GET", session+"/url", nil, creds)
+	r.recordStep(cloud, hub+"/session/{id}/url", "GET current URL", elapsed, err)
+	if err != nil {
+		r.printf("%s GET current URL failed after %s: %v\n", markFail, elapsed.Round(time.Millisecond), err)
+	} else {
+		r.printf("%s GET current URL answered in %s\n", markOK, elapsed.Round(time.Millisecond))
+	}
+
+	_, elapsed, err = r.webdriverCall("DELETE", session, nil, creds)
+	r.recordStep(cloud, hub+"/session/{id}", "DELETE session", elapsed, err)
+	if err != nil {
+		r.printf("%s DELETE session failed after %s: %v.  The session will time out on its own.\n", markFail, elapsed.Round(time.Millisecond), err)
+		return
+	}
+	r.printf("%s DELETE session answered in %s\n", markOK, elapsed.Round(time.Millisecond))
+}
+
+// recordStep stores one step of a session test
+func (r *Runner) recordStep(cloud, endpoint, step string, elapsed time.Duration, err error) {
+	res := Result{Group: cloud, Check: "session", Endpoint: endpoint, Outcome: Reachable, Detail: step, Duration: elapsed}
+	if err != nil {
+		res.fail(err)
+	}
+	r.record(res)
+}
+
+// webdriverCall sends one WebDriver command and turns WebDriver errors into Go errors
+func (r *Runner) webdriverCall(method, endpoint string, body []byte, creds credentials.Credentials) (webdriverValue, time.Duration, error) {
+	var wd webdriverValue
+	req, err := http.NewRequestWithContext(r.ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return wd, 0, err
+	}
+	req.SetBasicAuth(creds.Username, creds.AccessKey)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+
+	log.Debugf("Sending %s req to %s", method, endpoint)
+	start := time.Now()
+	resp, err := r.SessionClient.Do(req)
+	elapsed := time.Since(start)
+	if err != nil {
+		return wd, elapsed, err
+	}
+	defer resp.Body.Close()
+	raw, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return wd, elapsed, err
+	}
+	log.WithFields(log.Fields{
+		"status": resp.Status,
+		"resp":   resp,
+	}).Debugf("%s %s answered", method, endpoint)
+
+	var envelope webdriverResp
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return wd, elapsed, withCause(CauseProxyBlock, fmt.Errorf("%s and a body that is not WebDriver JSON", resp.Status))
+	}
+	// a non-object value such as the current URL is not an error
+	json.Unmarshal(envelope.Value, &wd)
+	if wd.SessionID == "" {
+		wd.SessionID = envelope.SessionID
+	}
+	if resp.StatusCode >= 300 || wd.Error != "" {
+		err := fmt.Errorf("%s", strings.TrimSpace(strings.Join([]string{resp.Status, wd.Error, wd.Message}, " ")))
+		if cause := statusCause(resp.StatusCode); cause != "" {
+			err = withCause(cause, err)
+		}
+		return wd, elapsed, err
+	}
+	return wd, elapsed, nil
+}
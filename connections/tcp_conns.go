@@ -1,51 +1,64 @@
 package connections
 
 import (
-	"fmt"
+	"context"
 	"net"
-	"net/url"
 	"time"
 
+	"github.com/mdsauce/nethelp/proxy"
 	log "github.com/sirupsen/logrus"
-	"golang.org/x/net/proxy"
 )
 
 // TCPConns attempts to open various TCP connections to the provided sites
 // This proves that with or without a proxy the TCP connections can be created.
-func TCPConns(sitelist []string, proxyURL *url.URL) {
-	if proxyURL != nil {
-		log.Warn("May fail if you are not using a SOCKS5 Proxy.")
-		var err error
-		var proxyDialer proxy.Dialer
-		proxyDialer, err = proxy.FromURL(proxyURL, proxy.Direct)
+// A failed site is reported and the remaining sites are still tried.
+func (r *Runner) TCPConns(sitelist []string) {
+	dial := r.tcpDialer(5 * time.Second)
+	for _, site := range sitelist {
+		start := time.Now()
+		conn, err := dial(r.ctx, site)
 		if err != nil {
-			log.Fatalf("Something went wrong while starting a proxy dialer for TCP conns.\n%v", err)
-		}
-		for _, site := range sitelist {
-			conn, err := proxyDialer.Dial("tcp4", site)
-			if err != nil {
-				log.Fatalf("%s unreachable, %v: ", site, err)
-			}
-			fmt.Println("[\u2713] TCP (IPv4) connection to", site)
+			r.printf("%s TCP (IPv4) connection to %s%s failed: %v\n", markFail, site, r.remapNote(site), err)
 			log.WithFields(log.Fields{
-				"local":  conn.LocalAddr(),
-				"remote": conn.RemoteAddr(),
-			}).Infof("[\u2713] %s reachable via TCP (IPv4).\n", site)
-			conn.Close()
+				"error": err,
+			}).Infof("%s %s unreachable via TCP (IPv4).\n", markFail, site)
+			res := Result{Group: "tcp", Check: "tcp", Endpoint: site, Duration: time.Since(start)}
+			res.fail(err)
+			r.record(res)
+			continue
 		}
-	} else {
-		for _, site := range sitelist {
-			timeout := time.Duration(5 * time.Second)
-			conn, err := net.DialTimeout("tcp4", site, timeout)
-			if err != nil {
-				log.Fatalf("%s unreachable, %v: ", site, err)
-			}
-			fmt.Println("[\u2713] TCP (IPv4) connection to", site)
-			log.WithFields(log.Fields{
-				"local":  conn.LocalAddr(),
-				"remote": conn.RemoteAddr(),
-			}).Infof("[\u2713] %s reachable via TCP (IPv4).\n", site)
-			conn.Close()
+		elapsed := time.Since(start)
+		r.printf("%s TCP (IPv4) connection to %s%s\n", markOK, site, r.remapNote(site))
+		log.WithFields(log.Fields{
+			"local":  conn.LocalAddr(),
+			"remote": conn.RemoteAddr(),
+		}).Infof("%s %s reachable via TCP (IPv4).\n", markOK, site)
+		r.record(Result{Group: "tcp", Check: "tcp", Endpoint: site, Outcome: Reachable, Detail: "remote " + conn.RemoteAddr().String(), Duration: elapsed})
+		conn.Close()
+	}
+}
+
+// tcpDialer opens IPv4 TCP connections that honour the remapping rules
+// and go through the proxy of the run, if any
+func (r *Runner) tcpDialer(timeout time.Duration) func(ctx context.Context, site string) (net.Conn, error) {
+	dial, err := r.dialer(timeout)
+	if err != nil {
+		log.Warnf("Something went wrong while starting a proxy dialer for TCP conns.\n%v", err)
+		return func(ctx context.Context, site string) (net.Conn, error) {
+			return nil, err
 		}
 	}
+	return func(ctx context.Context, site string) (net.Conn, error) {
+		return dial(ctx, "tcp4", site)
+	}
+}
+
+// dialer opens connections that honour the remapping rules and are
+// tunneled through the proxy of the run, if any, whatever its scheme
+func (r *Runner) dialer(timeout time.Duration) (proxy.DialFunc, error) {
+	forward := r.Remap.DialContext(&net.Dialer{Timeout: timeout})
+	if r.Proxy == nil {
+		return forward, nil
+	}
+	return proxy.Dialer(r.Proxy, forward)
 }
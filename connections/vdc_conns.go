@@ -2,17 +2,17 @@ package connections
 
 import (
 	"bytes"
-	"fmt"
 	"net/http"
 	"net/url"
-	"os"
+	"time"
 
+	"github.com/mdsauce/nethelp/credentials"
 	log "github.com/sirupsen/logrus"
 )
 
 // VDCServices sends HTTP requests to Sauce endpoints to prove
 // tests could theoretically be created and the data centers are reachable
-func VDCServices(sauceEndpoints []string) {
+func (r *Runner) VDCServices(sauceEndpoints []string) {
 	for _, endpoint := range sauceEndpoints {
 		u, err := url.ParseRequestURI(endpoint)
 		if err != nil {
@@ -20,7 +20,10 @@ func VDCServices(sauceEndpoints []string) {
 				"err":      err,
 				"endpoint": endpoint,
 			}).Debug("Could not parse endpoint.")
-			fmt.Printf("[ ] %s is not reachable. Err: %v\n", endpoint, err)
+			r.printf("[ ] %s is not reachable. Err: %v\n", endpoint, err)
+			res := Result{Group: "vdc", Check: "http", Endpoint: endpoint}
+			res.fail(err)
+			r.record(res)
 			continue
 		}
 		log.WithFields(log.Fields{
@@ -32,18 +35,22 @@ func VDCServices(sauceEndpoints []string) {
 		}).Debug("URL after Parsing")
 
 		log.Debug("Sending GET req to ", u)
-		resp, err := http.Get(u.String())
+		start := time.Now()
+		resp, err := r.get(u.String())
 		if err != nil {
-			fmt.Printf("[ ] %s not reachable\n", u)
+			r.printf("[ ] %s not reachable%s\n", u, r.remapNote(endpoint))
 			log.WithFields(log.Fields{
 				"error":    err,
 				"endpoint": u,
 			}).Infof("[ ] %s not reachable\n", u)
 		}
 
+		res := httpResult("vdc", endpoint, start, resp, err)
 		if err == nil {
-			respOutput(resp, endpoint)
+			r.respOutput(resp, endpoint, res)
+			resp.Body.Close()
 		}
+		r.record(res)
 	}
 }
 
@@ -51,28 +58,41 @@ func VDCServices(sauceEndpoints []string) {
 // 1) credentials work
 // 2) api is reachable
 // 3) api retrieves the expected data if 1 & 2 are true
-func VdcAPI(vdcRESTEndpoints []string) {
+// A 401 or 403 is reported as reachable but unauthorized, never as success.
+func (r *Runner) VdcAPI(vdcRESTEndpoints []string, creds credentials.Credentials) {
 	log.Debug("Sending out HTTP reqs to these endpoints: ", vdcRESTEndpoints)
-	username := os.Getenv("SAUCE_USERNAME")
-	apiKey := os.Getenv("SAUCE_ACCESS_KEY")
+	r.printf("Credentials for %s: %s\n", creds.Region, creds.Describe())
+	username := creds.Username
+	apiKey := creds.AccessKey
 	for _, endpoint := range vdcRESTEndpoints {
 		log.Debug("Sending GET req to ", endpoint)
 		var jsonBody = []byte(`{}`)
-		req, err := http.NewRequest("GET", endpoint, bytes.NewBuffer(jsonBody))
+		req, err := http.NewRequestWithContext(r.ctx, "GET", endpoint, bytes.NewBuffer(jsonBody))
+		if err != nil {
+			r.printf("[ ] %s is not reachable. Err: %v\n", endpoint, err)
+			continue
+		}
 		req.SetBasicAuth(username, apiKey)
 		req.Header.Set("Content-Type", "application/json")
 
-		client := &http.Client{}
-		resp, err := client.Do(req)
+		start := time.Now()
+		resp, err := r.Client.Do(req)
 		if err != nil {
-			fmt.Printf("[ ] %s not reachable\n", endpoint)
+			r.printf("[ ] %s not reachable%s\n", endpoint, r.remapNote(endpoint))
 			log.WithFields(log.Fields{
 				"error": err,
 			}).Infof("[ ] %s not reachable\n", endpoint)
+			res := Result{Group: "vdc", Check: "api", Endpoint: endpoint, Duration: time.Since(start)}
+			res.fail(err)
+			r.record(res)
 		}
 
 		if err == nil {
-			respOutput(resp, endpoint)
+			res := Result{Group: "vdc", Check: "api", Endpoint: endpoint}
+			res.fromResponse(resp)
+			res.Outcome, res.Detail, res.Cause = r.apiOutput(resp, endpoint, username, res)
+			res.Duration = time.Since(start)
+			r.record(res)
 		}
 	}
 }
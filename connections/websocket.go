@@ -0,0 +1,143 @@
+package connections
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/mdsauce/nethelp/echo"
+	log "github.com/sirupsen/logrus"
+)
+
+// wsMessage is the text the WebSocket check expects back from the echo target
+const wsMessage = "nethelp websocket check"
+
+// WebSocketCheck opens a WebSocket to an echo target, the /ws path of the
+// mock or the idle server, sends one message and waits for it to come
+// back.  The connection is tunneled through the proxy of the run whatever
+// its scheme, with CONNECT for HTTP proxies even for ws:// URLs, the way
+// browsers do it.
+func (r *Runner) WebSocketCheck(target string) {
+	res := Result{Group: "network", Check: "websocket", Endpoint: target}
+	start := time.Now()
+	err := r.websocket(target, &res)
+	res.Duration = time.Since(start)
+	if err != nil {
+		res.fail(err)
+		r.printf("%s WebSocket to %s%s: %v\n", markFail, target, r.remapNote(target), err)
+		r.record(res)
+		return
+	}
+	res.Outcome = Reachable
+	r.printf("%s WebSocket to %s%s echoed a message\n", markOK, target, r.remapNote(target))
+	r.record(res)
+}
+
+// websocket does the handshake and the echo of one WebSocket check
+func (r *Runner) websocket(target string, res *Result) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	var secure bool
+	port := "80"
+	switch u.Scheme {
+	case "wss", "https":
+		secure, port = true, "443"
+		u.Scheme = "https"
+	case "ws", "http":
+		u.Scheme = "http"
+	default:
+		return fmt.Errorf("%s is not a ws:// or wss:// URL", target)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	ctx, cancel := context.WithTimeout(r.ctx, 10*time.Second)
+	defer cancel()
+	dial, err := r.dialer(5 * time.Second)
+	if err != nil {
+		return err
+	}
+	conn, err := dial(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	if secure {
+		// like the HTTP checks, a proxied run does not verify certificates
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: u.Hostname(), InsecureSkipVerify: r.Proxy != nil, NextProtos: []string{"http/1.1"}})
+		if err := tlsConn.Handshake(); err != nil {
+			return err
+		}
+		state := tlsConn.ConnectionState()
+		res.TLSVersion = tlsVersions[state.Version]
+		res.ALPN = state.NegotiatedProtocol
+		if len(state.PeerCertificates) > 0 {
+			res.TLSIssuer = state.PeerCertificates[0].Issuer.String()
+		}
+		conn = tlsConn
+	}
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if err := req.Write(conn); err != nil {
+		return err
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return err
+	}
+	res.StatusCode = resp.StatusCode
+	res.Status = resp.Status
+	res.Proto = resp.Proto
+	log.WithFields(log.Fields{
+		"status":  resp.Status,
+		"upgrade": resp.Header.Get("Upgrade"),
+	}).Debugf("WebSocket handshake with %s", target)
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		resp.Body.Close()
+		return withCause(CauseProxyBlock, fmt.Errorf("answered %s instead of switching protocols", resp.Status))
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != echo.AcceptKey(key) {
+		return withCause(CauseProxyBlock, errors.New("the handshake was answered by something in between"))
+	}
+
+	if err := echo.WriteFrame(conn, echo.OpText, []byte(wsMessage), true); err != nil {
+		return err
+	}
+	opcode, payload, err := echo.ReadFrame(br)
+	if err != nil {
+		return fmt.Errorf("no echo after the handshake: %w", err)
+	}
+	if opcode != echo.OpText || !bytes.Equal(payload, []byte(wsMessage)) {
+		return withCause(CauseProxyBlock, errors.New("the message came back different, it was changed on the way"))
+	}
+	echo.WriteFrame(conn, echo.OpClose, nil, true)
+	return nil
+}
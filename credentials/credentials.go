@@ -0,0 +1,195 @@
+package credentials
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mdsauce/nethelp/config"
+	"github.com/mdsauce/nethelp/redact"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+// Source says where a username or access key was found
+type Source string
+
+// Credentials are resolved from these sources, first match wins
+const (
+	FromFlag   Source = "command line flag"
+	FromFile   Source = "credentials file"
+	FromEnv    Source = "environment variable"
+	FromPrompt Source = "interactive prompt"
+)
+
+// Credentials for one region.  String() masks the access key so
+// the struct is safe to log.
+type Credentials struct {
+	Region          string
+	Username        string
+	AccessKey       string
+	UsernameSource  Source
+	AccessKeySource Source
+}
+
+// Masked never shows the access key itself, only a short SHA-256
+// fingerprint so two different keys can still be told apart
+func (c Credentials) Masked() string {
+	if c.AccessKey == "" {
+		return "(none)"
+	}
+	sum := sha256.Sum256([]byte(c.AccessKey))
+	return "sha256:" + hex.EncodeToString(sum[:])[:6]
+}
+
+func (c Credentials) String() string {
+	return fmt.Sprintf("%s/%s", c.Username, c.Masked())
+}
+
+// Describe reports where the username and access key came from
+func (c Credentials) Describe() string {
+	return fmt.Sprintf("username %s from %s, access key %s from %s", c.Username, c.UsernameSource, c.Masked(), c.AccessKeySource)
+}
+
+// Provider resolves credentials in this order:
+// 1) the --username and --access-key flags
+// 2) the credentials file, region section first, then the default section
+// 3) SAUCE_USERNAME and the access key variable of the cloud
+// 4) an interactive prompt, if Prompt is set and stdin is a terminal
+type Provider struct {
+	Username  string
+	AccessKey string
+	File      string
+	Prompt    bool
+
+	file     *viper.Viper
+	prompted map[string]string
+	mu       sync.Mutex
+}
+
+// DefaultFile is credentials.yaml in the nethelp config directory
+func DefaultFile() string {
+	dir, err := config.Dir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "credentials.yaml")
+}
+
+// NewProvider builds a Provider from the flag values and the default credentials file
+func NewProvider(username, accessKey string, prompt bool) *Provider {
+	return &Provider{
+		Username:  username,
+		AccessKey: accessKey,
+		File:      DefaultFile(),
+		Prompt:    prompt,
+	}
+}
+
+func (p *Provider) loadFile() *viper.Viper {
+	if p.file != nil || p.File == "" {
+		return p.file
+	}
+	if _, err := os.Stat(p.File); err != nil {
+		log.Debug("No credentials file at ", p.File)
+		return nil
+	}
+	v := viper.New()
+	v.SetConfigFile(p.File)
+	if err := v.ReadInConfig(); err != nil {
+		log.Warnf("Could not read the credentials file %s.  %v", p.File, err)
+		return nil
+	}
+	p.file = v
+	return v
+}
+
+// fromFile looks up key in the region section, then in the default section
+func (p *Provider) fromFile(region, key string) string {
+	v := p.loadFile()
+	if v == nil {
+		return ""
+	}
+	if val := v.GetString(region + "." + key); val != "" {
+		return val
+	}
+	return v.GetString("default." + key)
+}
+
+// prompt asks once per question and remembers the answer for other regions
+func (p *Provider) prompt(question string, hidden bool) string {
+	if !p.Prompt || !term.IsTerminal(int(os.Stdin.Fd())) {
+		return ""
+	}
+	if answer, ok := p.prompted[question]; ok {
+		return answer
+	}
+	fmt.Fprint(os.Stderr, question)
+	var answer string
+	if hidden {
+		b, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			log.Warn("Could not read from the terminal. ", err)
+			return ""
+		}
+		answer = string(b)
+	} else {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			log.Warn("Could not read from the terminal. ", err)
+			return ""
+		}
+		answer = line
+	}
+	answer = strings.TrimSpace(answer)
+	if p.prompted == nil {
+		p.prompted = make(map[string]string)
+	}
+	p.prompted[question] = answer
+	return answer
+}
+
+// Lookup resolves the credentials for region.  keyEnv is the environment
+// variable holding the access key for the cloud, e.g. SAUCE_ACCESS_KEY.
+func (p *Provider) Lookup(region, keyEnv string) (Credentials, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c := Credentials{Region: region}
+
+	switch {
+	case p.Username != "":
+		c.Username, c.UsernameSource = p.Username, FromFlag
+	case p.fromFile(region, "username") != "":
+		c.Username, c.UsernameSource = p.fromFile(region, "username"), FromFile
+	case os.Getenv("SAUCE_USERNAME") != "":
+		c.Username, c.UsernameSource = os.Getenv("SAUCE_USERNAME"), FromEnv+" SAUCE_USERNAME"
+	default:
+		c.Username, c.UsernameSource = p.prompt("Sauce Labs username: ", false), FromPrompt
+	}
+	if c.Username == "" {
+		return c, fmt.Errorf("no username found for %s.  Use --username, %s or SAUCE_USERNAME", region, p.File)
+	}
+
+	switch {
+	case p.AccessKey != "":
+		c.AccessKey, c.AccessKeySource = p.AccessKey, FromFlag
+	case p.fromFile(region, "access_key") != "":
+		c.AccessKey, c.AccessKeySource = p.fromFile(region, "access_key"), FromFile
+	case os.Getenv(keyEnv) != "":
+		c.AccessKey, c.AccessKeySource = os.Getenv(keyEnv), FromEnv+Source(" "+keyEnv)
+	default:
+		c.AccessKey, c.AccessKeySource = p.prompt(fmt.Sprintf("%s for %s: ", keyEnv, c.Username), true), FromPrompt
+	}
+	if c.AccessKey == "" {
+		return c, fmt.Errorf("no access key found for %s.  Use --access-key, %s or %s", region, p.File, keyEnv)
+	}
+	redact.AddCredentials(c.Username, c.AccessKey)
+	return c, nil
+}
@@ -0,0 +1,192 @@
+package credentials
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setenv sets an environment variable for the rest of the test
+func setenv(t *testing.T, key, value string) {
+	old, had := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if had {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+const credentialsFile = `default:
+  username: file-user
+  access_key: file-key
+eu-central:
+  username: eu-user
+east:
+  access_key: east-key
+`
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name          string
+		username      string
+		accessKey     string
+		file          bool
+		env           map[string]string
+		region        string
+		keyEnv        string
+		wantUsername  string
+		wantUserFrom  Source
+		wantAccessKey string
+		wantKeyFrom   Source
+		wantErr       bool
+	}{
+		{
+			name:          "flags beat everything",
+			username:      "flag-user",
+			accessKey:     "flag-key",
+			file:          true,
+			env:           map[string]string{"SAUCE_USERNAME": "env-user", "SAUCE_ACCESS_KEY": "env-key"},
+			region:        "us-west",
+			keyEnv:        "SAUCE_ACCESS_KEY",
+			wantUsername:  "flag-user",
+			wantUserFrom:  FromFlag,
+			wantAccessKey: "flag-key",
+			wantKeyFrom:   FromFlag,
+		},
+		{
+			name:          "file beats the environment",
+			file:          true,
+			env:           map[string]string{"SAUCE_USERNAME": "env-user", "SAUCE_ACCESS_KEY": "env-key"},
+			region:        "us-west",
+			keyEnv:        "SAUCE_ACCESS_KEY",
+			wantUsername:  "file-user",
+			wantUserFrom:  FromFile,
+			wantAccessKey: "file-key",
+			wantKeyFrom:   FromFile,
+		},
+		{
+			name:          "region section before the default section",
+			file:          true,
+			region:        "eu-central",
+			keyEnv:        "SAUCE_ACCESS_KEY",
+			wantUsername:  "eu-user",
+			wantUserFrom:  FromFile,
+			wantAccessKey: "file-key",
+			wantKeyFrom:   FromFile,
+		},
+		{
+			name:          "flag and file mixed",
+			accessKey:     "flag-key",
+			file:          true,
+			region:        "us-west",
+			keyEnv:        "SAUCE_ACCESS_KEY",
+			wantUsername:  "file-user",
+			wantUserFrom:  FromFile,
+			wantAccessKey: "flag-key",
+			wantKeyFrom:   FromFlag,
+		},
+		{
+			name:          "environment without a file",
+			env:           map[string]string{"SAUCE_USERNAME": "env-user", "SAUCE_ACCESS_KEY": "env-key"},
+			region:        "us-west",
+			keyEnv:        "SAUCE_ACCESS_KEY",
+			wantUsername:  "env-user",
+			wantUserFrom:  FromEnv + " SAUCE_USERNAME",
+			wantAccessKey: "env-key",
+			wantKeyFrom:   FromEnv + " SAUCE_ACCESS_KEY",
+		},
+		{
+			name:          "headless key from the environment",
+			env:           map[string]string{"SAUCE_USERNAME": "env-user", "SAUCE_ACCESS_KEY": "env-key", "HEADLESS_ACCESS_KEY": "headless-key"},
+			region:        "east",
+			keyEnv:        "HEADLESS_ACCESS_KEY",
+			wantUsername:  "env-user",
+			wantUserFrom:  FromEnv + " SAUCE_USERNAME",
+			wantAccessKey: "headless-key",
+			wantKeyFrom:   FromEnv + " HEADLESS_ACCESS_KEY",
+		},
+		{
+			name:          "headless key from the file",
+			file:          true,
+			env:           map[string]string{"HEADLESS_ACCESS_KEY": "headless-key"},
+			region:        "east",
+			keyEnv:        "HEADLESS_ACCESS_KEY",
+			wantUsername:  "file-user",
+			wantUserFrom:  FromFile,
+			wantAccessKey: "east-key",
+			wantKeyFrom:   FromFile,
+		},
+		{
+			name:         "headless does not use SAUCE_ACCESS_KEY",
+			env:          map[string]string{"SAUCE_USERNAME": "env-user", "SAUCE_ACCESS_KEY": "env-key"},
+			region:       "east",
+			keyEnv:       "HEADLESS_ACCESS_KEY",
+			wantUsername: "env-user",
+			wantUserFrom: FromEnv + " SAUCE_USERNAME",
+			wantKeyFrom:  FromPrompt,
+			wantErr:      true,
+		},
+		{
+			name:         "nothing to find and no prompt",
+			region:       "us-west",
+			keyEnv:       "SAUCE_ACCESS_KEY",
+			wantUserFrom: FromPrompt,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			setenv(t, "NETHELP_CONFIG_DIR", dir)
+			if tt.file {
+				if err := ioutil.WriteFile(filepath.Join(dir, "credentials.yaml"), []byte(credentialsFile), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for _, key := range []string{"SAUCE_USERNAME", "SAUCE_ACCESS_KEY", "HEADLESS_ACCESS_KEY"} {
+				setenv(t, key, tt.env[key])
+			}
+
+			// no prompt, go test may run with a terminal on stdin
+			p := NewProvider(tt.username, tt.accessKey, false)
+			if p.File != filepath.Join(dir, "credentials.yaml") {
+				t.Fatalf("File = %s, want credentials.yaml in %s", p.File, dir)
+			}
+			c, err := p.Lookup(tt.region, tt.keyEnv)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Lookup() error = %v, want error %v", err, tt.wantErr)
+			}
+			if c.Username != tt.wantUsername || c.UsernameSource != tt.wantUserFrom {
+				t.Errorf("username = %q from %q, want %q from %q", c.Username, c.UsernameSource, tt.wantUsername, tt.wantUserFrom)
+			}
+			if c.AccessKey != tt.wantAccessKey || c.AccessKeySource != tt.wantKeyFrom {
+				t.Errorf("access key = %q from %q, want %q from %q", c.AccessKey, c.AccessKeySource, tt.wantAccessKey, tt.wantKeyFrom)
+			}
+		})
+	}
+}
+
+func TestMasked(t *testing.T) {
+	if got := (Credentials{Username: "bob"}).Masked(); got != "(none)" {
+		t.Errorf("Masked() without a key = %q, want (none)", got)
+	}
+	// the sha256 of abc starts with ba7816
+	c := Credentials{Username: "bob", AccessKey: "abc"}
+	if got := c.Masked(); got != "sha256:ba7816" {
+		t.Errorf("Masked() = %q, want sha256:ba7816", got)
+	}
+	if got := c.String(); got != "bob/sha256:ba7816" {
+		t.Errorf("String() = %q, want bob/sha256:ba7816", got)
+	}
+	if other := (Credentials{AccessKey: "abd"}).Masked(); other == c.Masked() {
+		t.Errorf("two keys share the fingerprint %s", other)
+	}
+	if strings.Contains(c.Describe(), "abc") {
+		t.Errorf("Describe() shows the access key: %s", c.Describe())
+	}
+}
@@ -0,0 +1,47 @@
+// Package echo answers every request with what arrived, so a client can
+// tell whether a proxy changed the method or the body on the way.
+package echo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// Path is where the mock and the idle server serve the echo handler
+const Path = "/echo"
+
+// Reply describes the request as the server saw it
+type Reply struct {
+	Method string `json:"method"`
+	Length int64  `json:"length"`
+	SHA256 string `json:"sha256"`
+	Expect string `json:"expect,omitempty"`
+}
+
+// Sum is the hex SHA-256 of body, as Reply reports it
+func Sum(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// Handler reads the whole body and answers with a Reply as JSON
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hash := sha256.New()
+		n, err := io.Copy(hash, r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Reply{
+			Method: r.Method,
+			Length: n,
+			SHA256: hex.EncodeToString(hash.Sum(nil)),
+			Expect: r.Header.Get("Expect"),
+		})
+	})
+}
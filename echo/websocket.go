@@ -0,0 +1,163 @@
+package echo
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// WebSocketPath is where the mock and the idle server echo WebSocket messages
+const WebSocketPath = "/ws"
+
+// WebSocket opcodes, RFC 6455 section 5.2
+const (
+	OpText  = 0x1
+	OpClose = 0x8
+	OpPing  = 0x9
+	OpPong  = 0xa
+)
+
+// maxFrame bounds the payload of one frame, the check sends a few bytes
+const maxFrame = 1 << 20
+
+// AcceptKey is the Sec-WebSocket-Accept answer to key
+func AcceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// WriteFrame writes one final frame.  Clients mask their frames, servers do not.
+func WriteFrame(w io.Writer, opcode byte, payload []byte, mask bool) error {
+	head := []byte{0x80 | opcode, 0}
+	switch n := len(payload); {
+	case n < 126:
+		head[1] = byte(n)
+	case n <= 0xffff:
+		head[1] = 126
+		head = append(head, 0, 0)
+		binary.BigEndian.PutUint16(head[2:], uint16(n))
+	default:
+		head[1] = 127
+		head = append(head, make([]byte, 8)...)
+		binary.BigEndian.PutUint64(head[2:], uint64(n))
+	}
+	body := payload
+	if mask {
+		head[1] |= 0x80
+		key := make([]byte, 4)
+		if _, err := rand.Read(key); err != nil {
+			return err
+		}
+		head = append(head, key...)
+		body = make([]byte, len(payload))
+		for i := range payload {
+			body[i] = payload[i] ^ key[i%4]
+		}
+	}
+	_, err := w.Write(append(head, body...))
+	return err
+}
+
+// ReadFrame reads one frame and unmasks its payload.  Fragmented messages
+// are not supported, nothing nethelp sends needs them.
+func ReadFrame(r io.Reader) (byte, []byte, error) {
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return 0, nil, err
+	}
+	if head[0]&0x80 == 0 {
+		return 0, nil, errors.New("fragmented WebSocket frame")
+	}
+	opcode := head[0] & 0x0f
+	n := uint64(head[1] & 0x7f)
+	switch n {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext)
+	}
+	if n > maxFrame {
+		return 0, nil, fmt.Errorf("WebSocket frame of %d bytes is too large", n)
+	}
+	var key []byte
+	if head[1]&0x80 != 0 {
+		key = make([]byte, 4)
+		if _, err := io.ReadFull(r, key); err != nil {
+			return 0, nil, err
+		}
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	if key != nil {
+		for i := range payload {
+			payload[i] ^= key[i%4]
+		}
+	}
+	return opcode, payload, nil
+}
+
+// WebSocket upgrades the request and sends every message back until the
+// client closes the connection
+func WebSocket() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Sec-WebSocket-Key")
+		if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || key == "" {
+			http.Error(w, "expected a WebSocket upgrade", http.StatusBadRequest)
+			return
+		}
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			http.Error(w, "the connection cannot be upgraded", http.StatusInternalServerError)
+			return
+		}
+		conn, rw, err := hijacker.Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", AcceptKey(key))
+		if rw.Flush() != nil {
+			return
+		}
+		serveFrames(rw.Reader, conn)
+	})
+}
+
+// serveFrames echoes data frames, answers pings and returns on close
+func serveFrames(r *bufio.Reader, w io.Writer) {
+	for {
+		opcode, payload, err := ReadFrame(r)
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case OpClose:
+			WriteFrame(w, OpClose, payload, false)
+			return
+		case OpPing:
+			opcode = OpPong
+		case OpPong:
+			continue
+		}
+		if WriteFrame(w, opcode, payload, false) != nil {
+			return
+		}
+	}
+}
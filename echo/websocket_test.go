@@ -0,0 +1,115 @@
+package echo
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		opcode  byte
+		payload []byte
+		mask    bool
+	}{
+		{"empty close", OpClose, nil, false},
+		{"short text from the client", OpText, []byte("nethelp websocket check"), true},
+		{"short text from the server", OpText, []byte("nethelp websocket check"), false},
+		{"16 bit length", OpText, bytes.Repeat([]byte("a"), 126), true},
+		{"largest 16 bit length", OpPing, bytes.Repeat([]byte("b"), 0xffff), false},
+		{"64 bit length", OpText, bytes.Repeat([]byte("c"), 0x10000), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteFrame(&buf, tt.opcode, tt.payload, tt.mask); err != nil {
+				t.Fatal(err)
+			}
+			if masked := buf.Bytes()[1]&0x80 != 0; masked != tt.mask {
+				t.Errorf("mask bit = %v, want %v", masked, tt.mask)
+			}
+			opcode, payload, err := ReadFrame(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if opcode != tt.opcode || !bytes.Equal(payload, tt.payload) {
+				t.Errorf("ReadFrame() = %#x with %d bytes, want %#x with %d bytes", opcode, len(payload), tt.opcode, len(tt.payload))
+			}
+			if buf.Len() != 0 {
+				t.Errorf("%d bytes left after the frame", buf.Len())
+			}
+		})
+	}
+}
+
+func TestReadFrameRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame []byte
+	}{
+		{"fragment", []byte{0x01, 0x00}},
+		{"too large", []byte{0x81, 127, 0, 0, 0, 0, 0x10, 0, 0, 0}},
+		{"cut off payload", []byte{0x81, 5, 'a', 'b'}},
+	}
+	for _, tt := range tests {
+		if _, _, err := ReadFrame(bytes.NewReader(tt.frame)); err == nil {
+			t.Errorf("%s: ReadFrame() accepted %v", tt.name, tt.frame)
+		}
+	}
+}
+
+func TestAcceptKey(t *testing.T) {
+	// the example handshake of RFC 6455 section 1.3
+	if got, want := AcceptKey("dGhlIHNhbXBsZSBub25jZQ=="), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="; got != want {
+		t.Errorf("AcceptKey() = %q, want %q", got, want)
+	}
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	server := httptest.NewServer(WebSocket())
+	defer server.Close()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	const key = "dGhlIHNhbXBsZSBub25jZQ=="
+	fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: echo\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n\r\n", WebSocketPath, key)
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != AcceptKey(key) {
+		t.Fatalf("handshake answered %s with accept key %q", resp.Status, resp.Header.Get("Sec-WebSocket-Accept"))
+	}
+
+	for _, frame := range []struct {
+		opcode, want byte
+		payload      string
+	}{
+		{OpText, OpText, "nethelp websocket check"},
+		{OpPing, OpPong, "are you there"},
+		{OpClose, OpClose, ""},
+	} {
+		if err := WriteFrame(conn, frame.opcode, []byte(frame.payload), true); err != nil {
+			t.Fatal(err)
+		}
+		opcode, payload, err := ReadFrame(br)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if opcode != frame.want || string(payload) != frame.payload {
+			t.Errorf("sent %#x %q, got %#x %q back", frame.opcode, frame.payload, opcode, payload)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package endpoints
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultCustomGroup is the result group of user defined checks that do not name one
+const DefaultCustomGroup = "custom"
+
+// Custom extends Check with user defined checks loaded from YAML.
+// Sitelist holds the URL or address of every check, in file order.
+type Custom struct {
+	Check  `yaml:"-"`
+	Group  string        `yaml:"group"`
+	Checks []CustomCheck `yaml:"checks"`
+}
+
+// CustomCheck is one user defined check.  A URL sends an HTTP request
+// and evaluates the assertions, an Address only opens a TCP connection.
+type CustomCheck struct {
+	Name    string            `yaml:"name"`
+	URL     string            `yaml:"url"`
+	Address string            `yaml:"address"`
+	Method  string            `yaml:"method"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+	// Status lists the status codes that pass, 200 when empty
+	Status []int `yaml:"status"`
+	// BodyRegex must match somewhere in the response body
+	BodyRegex string `yaml:"body_regex"`
+	// JSONPath is a dotted path like value.ready or items.0.id that must
+	// exist in the JSON body, and equal JSONEquals when that is set
+	JSONPath   string        `yaml:"json_path"`
+	JSONEquals string        `yaml:"json_equals"`
+	Timeout    time.Duration `yaml:"timeout"`
+
+	bodyRegex *regexp.Regexp
+}
+
+// Target is the URL or address the check connects to
+func (c CustomCheck) Target() string {
+	if c.URL != "" {
+		return c.URL
+	}
+	return c.Address
+}
+
+// BodyPattern is the compiled BodyRegex, nil when the check has none
+func (c CustomCheck) BodyPattern() *regexp.Regexp {
+	return c.bodyRegex
+}
+
+// Passes tells whether status is one of the expected status codes
+func (c CustomCheck) Passes(status int) bool {
+	for _, s := range c.Status {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadCustom reads user defined checks from a YAML file like
+//
+//	group: acme
+//	checks:
+//	  - name: staging app
+//	    url: https://staging.acme.com/health
+//	    status: [200, 204]
+//	    body_regex: '"status":\s*"ok"'
+//	    timeout: 5s
+//	  - name: artifact repo
+//	    address: nexus.acme.internal:443
+func LoadCustom(path string) (Custom, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Custom{}, err
+	}
+	var custom Custom
+	if err := yaml.UnmarshalStrict(raw, &custom); err != nil {
+		return Custom{}, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := custom.Validate(); err != nil {
+		return Custom{}, fmt.Errorf("%s: %v", path, err)
+	}
+	return custom, nil
+}
+
+// Validate fills in the defaults of every check, compiles the body
+// regexes and rebuilds Sitelist.  LoadCustom calls it, checks built in
+// Go code go through it when a suite runs them.
+func (c *Custom) Validate() error {
+	if c.Group == "" {
+		c.Group = DefaultCustomGroup
+	}
+	if len(c.Checks) == 0 {
+		return fmt.Errorf("no checks defined")
+	}
+	// the checks may belong to the caller, defaults go to a copy
+	c.Checks = append([]CustomCheck(nil), c.Checks...)
+	c.Sitelist = nil
+	for i := range c.Checks {
+		if err := c.Checks[i].validate(); err != nil {
+			return fmt.Errorf("check %d: %v", i+1, err)
+		}
+		c.Sitelist = append(c.Sitelist, c.Checks[i].Target())
+	}
+	return nil
+}
+
+// validate fills in defaults and rejects checks that cannot run
+func (c *CustomCheck) validate() error {
+	if (c.URL == "") == (c.Address == "") {
+		return fmt.Errorf("set either url or address")
+	}
+	if c.Name == "" {
+		c.Name = c.Target()
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+	}
+	if c.Address != "" {
+		if _, _, err := net.SplitHostPort(c.Address); err != nil {
+			return fmt.Errorf("address %s must look like host:port", c.Address)
+		}
+		if c.Method != "" || c.Body != "" || len(c.Headers) > 0 || len(c.Status) > 0 || c.BodyRegex != "" || c.JSONPath != "" {
+			return fmt.Errorf("address %s is a TCP check, HTTP options do not apply", c.Address)
+		}
+		return nil
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url %s must look like http(s)://host/path", c.URL)
+	}
+	c.Method = strings.ToUpper(c.Method)
+	if c.Method == "" {
+		c.Method = http.MethodGet
+	}
+	if len(c.Status) == 0 {
+		c.Status = []int{http.StatusOK}
+	}
+	if c.BodyRegex != "" {
+		if c.bodyRegex, err = regexp.Compile(c.BodyRegex); err != nil {
+			return fmt.Errorf("body_regex: %v", err)
+		}
+	}
+	if c.JSONEquals != "" && c.JSONPath == "" {
+		return fmt.Errorf("json_equals needs a json_path")
+	}
+	return nil
+}
@@ -1,44 +1,17 @@
 package endpoints
 
-import (
-	"fmt"
-	"os"
+import "github.com/mdsauce/nethelp/credentials"
 
-	log "github.com/sirupsen/logrus"
-)
-
-// AssembleHeadlessEndpoints interpolates user variables like
-// SAUCE_USERNAME and SAUCE_ACCESS_KEY to create a valid URI.
-func AssembleHeadlessEndpoints(dc string) *SauceService {
-	if os.Getenv("SAUCE_USERNAME") == "" {
-		log.Info("SAUCE_USERNAME environment variables not found.  Not running Headless REST endpoint tests.")
-		return nil
-	}
-	if os.Getenv("HEADLESS_ACCESS_KEY") == "" {
-		log.Info("HEADLESS_ACCESS_KEY environment variable not found.  Not running Headless REST endpoint tests.")
-		return nil
-	}
-	eastHeadless := fmt.Sprintf("https://us-east-1.saucelabs.com/rest/v1/users/%s", os.Getenv("SAUCE_USERNAME"))
-
-	switch dc {
-	case "all":
-		e := make([]string, 1)
-		e[0] = eastHeadless
-		return &SauceService{Datacenter: dc, Cloud: "headless", Endpoints: e}
-	case "east":
-		e := make([]string, 1)
-		e[0] = eastHeadless
-		return &SauceService{Datacenter: dc, Cloud: "headless", Endpoints: e}
-	default:
-		return nil
-	}
+// AssembleHeadlessEndpoints interpolates the username of every region
+// to create valid REST URIs, one SauceService per region.  Regions
+// without credentials are skipped.
+func AssembleHeadlessEndpoints(dc string, creds *credentials.Provider) []SauceService {
+	return assembleREST(dc, "headless", creds, "/rest/v1/users/%s")
 }
 
 // NewHeadlessTest constructs a SauceService object that contains the specificed Datacenter and endpoints
 func NewHeadlessTest(dc string) SauceService {
 	headlessTest := SauceService{Datacenter: dc, Cloud: "headless"}
-	if dc == "east" || dc == "all" {
-		headlessTest.Endpoints = []string{"http://ondemand.us-east-1.saucelabs.com:80", "https://ondemand.us-east-1.saucelabs.com:443"}
-	}
+	headlessTest.Endpoints = cloudURLs(dc, "headless")
 	return headlessTest
 }
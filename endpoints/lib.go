@@ -1,5 +1,13 @@
 package endpoints
 
+import (
+	"fmt"
+	"net"
+	"net/url"
+
+	"github.com/mdsauce/nethelp/credentials"
+)
+
 // Check is the target of endpoints that
 // should be reachable
 type Check struct {
@@ -7,22 +15,83 @@ type Check struct {
 }
 
 // SauceService is a combination of the
-// Cloud, Geographic location of the DC, and endpoint collection
+// Cloud, Geographic location of the DC, and endpoint collection.
+// Credentials are only set for REST API services.
 type SauceService struct {
-	Datacenter string
-	Cloud      string
-	Endpoints  []string
+	Datacenter  string
+	Cloud       string
+	Endpoints   []string
+	Credentials credentials.Credentials
+}
+
+// ParseBaseURL reads a URL that replaces the scheme and host of every
+// endpoint, used to point a whole run at a mock server
+func ParseBaseURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("base URL %s must look like http://host:port", rawURL)
+	}
+	return u, nil
 }
 
-// vdcNA = []string{"https://ondemand.saucelabs.com:443", "http://ondemand.saucelabs.com:80"}
-// vdcEU = []string{"http://ondemand.eu-central-1.saucelabs.com:80", "https://ondemand.eu-central-1.saucelabs.com:443"}
-// rdcNA = []string{"https://us1.appium.testobject.com/wd/hub/session"}
-// rdcEU = []string{"https://eu1.appium.testobject.com/wd/hub/session"}
+// rebase swaps the scheme and host of endpoint for base.
+// Paths like /rest/v1/... and /wd/hub/status are kept.
+func rebase(base *url.URL, endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return endpoint
+	}
+	u.Scheme = base.Scheme
+	u.Host = base.Host
+	return u.String()
+}
+
+// rebaseAddr swaps a host:port pair for the host:port of base
+func rebaseAddr(base *url.URL, addr string) string {
+	if base.Port() != "" {
+		return base.Host
+	}
+	if base.Scheme == "https" {
+		return net.JoinHostPort(base.Hostname(), "443")
+	}
+	return net.JoinHostPort(base.Hostname(), "80")
+}
+
+// Rebase points every endpoint URL at base, dropping the duplicates
+// that appear once every host points at the same server.  A nil base
+// returns list unchanged.
+func Rebase(base *url.URL, list []string) []string {
+	return rebaseAll(base, list, rebase)
+}
+
+// RebaseAddrs is Rebase for host:port pairs like the TCP sitelist
+func RebaseAddrs(base *url.URL, list []string) []string {
+	return rebaseAll(base, list, rebaseAddr)
+}
+
+func rebaseAll(base *url.URL, list []string, rebaser func(*url.URL, string) string) []string {
+	if base == nil {
+		return list
+	}
+	seen := make(map[string]bool)
+	var rebased []string
+	for _, e := range list {
+		r := rebaser(base, e)
+		if !seen[r] {
+			seen[r] = true
+			rebased = append(rebased, r)
+		}
+	}
+	return rebased
+}
 
-// NewTCPTest builds a new TCPTest object
+// NewTCPTest builds a new TCPTest object from the tcp_ports of every region
 func NewTCPTest() Check {
 	defaultTCP := Check{}
-	defaultTCP.Sitelist = []string{"ondemand.saucelabs.com:443", "ondemand.saucelabs.com:80", "ondemand.saucelabs.com:8080", "ondemand.eu-central-1.saucelabs.com:80", "ondemand.eu-central-1.saucelabs.com:443", "us1.appium.testobject.com:443", "eu1.appium.testobject.com:443", "us1.appium.testobject.com:80", "eu1.appium.testobject.com:80"}
+	defaultTCP.Sitelist = tcpAddrs()
 	return defaultTCP
 }
 
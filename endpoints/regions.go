@@ -0,0 +1,221 @@
+package endpoints
+
+import (
+	_ "embed" // needed for the region registry
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/mdsauce/nethelp/credentials"
+	log "github.com/sirupsen/logrus"
+)
+
+// registryJSON lists every data center and the hosts each cloud uses in it.
+// Add new regions to regions.json, not to the endpoint constructors.
+//
+//go:embed regions.json
+var registryJSON []byte
+
+var registry []Region
+
+// Region is a Sauce Labs data center, selected with --dc
+type Region struct {
+	Key     string                `json:"key"`
+	Name    string                `json:"name"`
+	Aliases []string              `json:"aliases"`
+	Clouds  map[string]CloudHosts `json:"clouds"`
+}
+
+// CloudHosts is where one cloud (vdc, rdc or headless) lives in a Region
+type CloudHosts struct {
+	Host     string `json:"host"`
+	Ports    []int  `json:"ports"`
+	Path     string `json:"path"`
+	TCPPorts []int  `json:"tcp_ports"`
+	REST     string `json:"rest"`
+	KeyEnv   string `json:"access_key_env"`
+	Hub      string `json:"hub"`
+}
+
+func init() {
+	if err := json.Unmarshal(registryJSON, &registry); err != nil {
+		log.Fatal("The embedded region registry is broken. ", err)
+	}
+}
+
+// URLs builds one endpoint per port, https for 443 and http for everything else
+func (c CloudHosts) URLs() []string {
+	var urls []string
+	for _, port := range c.Ports {
+		scheme := "http"
+		if port == 443 {
+			scheme = "https"
+		}
+		urls = append(urls, fmt.Sprintf("%s://%s:%d%s", scheme, c.Host, port, c.Path))
+	}
+	return urls
+}
+
+// AccessKeyEnv is the environment variable holding the access key for this cloud
+func (c CloudHosts) AccessKeyEnv() string {
+	if c.KeyEnv != "" {
+		return c.KeyEnv
+	}
+	return "SAUCE_ACCESS_KEY"
+}
+
+// Regions returns every data center in the registry
+func Regions() []Region {
+	return registry
+}
+
+// RegionKeys lists the values --dc accepts besides 'all'
+func RegionKeys() []string {
+	var keys []string
+	for _, r := range registry {
+		keys = append(keys, r.Key)
+	}
+	return keys
+}
+
+// LookupRegion finds a region by its key or one of its aliases
+func LookupRegion(dc string) (Region, bool) {
+	dc = strings.ToLower(dc)
+	for _, r := range registry {
+		if r.Key == dc {
+			return r, true
+		}
+		for _, alias := range r.Aliases {
+			if alias == dc {
+				return r, true
+			}
+		}
+	}
+	return Region{}, false
+}
+
+// RegionOf finds the region an endpoint URL or host:port belongs to.
+// Endpoints outside the registry, like public sites, return "".
+func RegionOf(endpoint string) string {
+	host := endpoint
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		host = u.Hostname()
+	} else if h, _, err := net.SplitHostPort(endpoint); err == nil {
+		host = h
+	}
+	for _, r := range registry {
+		for _, c := range r.Clouds {
+			for _, known := range []string{c.Host, c.REST, c.Hub} {
+				if known == "" {
+					continue
+				}
+				if u, err := url.Parse(known); err == nil && u.Host != "" {
+					known = u.Hostname()
+				}
+				if strings.EqualFold(known, host) {
+					return r.Key
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// regionsFor returns the selected region, or all of them for 'all'
+func regionsFor(dc string) []Region {
+	if dc == "all" {
+		return registry
+	}
+	if r, ok := LookupRegion(dc); ok {
+		return []Region{r}
+	}
+	return nil
+}
+
+// cloudURLs collects the endpoints of one cloud across the selected regions
+func cloudURLs(dc, cloud string) []string {
+	var urls []string
+	for _, r := range regionsFor(dc) {
+		if c, ok := r.Clouds[cloud]; ok {
+			urls = append(urls, c.URLs()...)
+		}
+	}
+	return urls
+}
+
+// assembleREST builds one SauceService per region of a cloud with a REST base,
+// filling the username of that region into every path in pathFormats
+func assembleREST(dc, cloud string, creds *credentials.Provider, pathFormats ...string) []SauceService {
+	return assemble(dc, cloud, creds, func(c CloudHosts) string { return c.REST }, pathFormats...)
+}
+
+// Hubs returns the WebDriver hub URL of every selected region of a cloud
+func Hubs(dc, cloud string) []string {
+	var hubs []string
+	for _, r := range regionsFor(dc) {
+		if c, ok := r.Clouds[cloud]; ok && c.Hub != "" {
+			hubs = append(hubs, c.Hub)
+		}
+	}
+	return hubs
+}
+
+// AssembleSessionHubs returns the WebDriver hub of every selected region
+// of a cloud, with the credentials to start a session there
+func AssembleSessionHubs(dc, cloud string, creds *credentials.Provider) []SauceService {
+	return assemble(dc, cloud, creds, func(c CloudHosts) string { return c.Hub })
+}
+
+// assemble builds one authenticated SauceService per region where base
+// returns a URL for the cloud.  Without pathFormats the base URL itself is the endpoint.
+func assemble(dc, cloud string, creds *credentials.Provider, base func(CloudHosts) string, pathFormats ...string) []SauceService {
+	var services []SauceService
+	var skipped []string
+	var lookupErr error
+	for _, r := range regionsFor(dc) {
+		c, ok := r.Clouds[cloud]
+		if !ok || base(c) == "" {
+			continue
+		}
+		auth, err := creds.Lookup(r.Key, c.AccessKeyEnv())
+		if err != nil {
+			skipped = append(skipped, r.Key)
+			lookupErr = err
+			continue
+		}
+		e := []string{base(c)}
+		if len(pathFormats) > 0 {
+			e = nil
+		}
+		for _, pathFormat := range pathFormats {
+			e = append(e, base(c)+fmt.Sprintf(pathFormat, auth.Username))
+		}
+		services = append(services, SauceService{Datacenter: r.Key, Cloud: cloud, Endpoints: e, Credentials: auth})
+	}
+	if len(skipped) > 0 {
+		log.Warnf("Not running %s tests that need credentials for %s.  %v", strings.ToUpper(cloud), strings.Join(skipped, ", "), lookupErr)
+	}
+	return services
+}
+
+// tcpAddrs lists host:port pairs for every cloud that has TCP ports defined
+func tcpAddrs() []string {
+	var addrs []string
+	for _, r := range registry {
+		clouds := make([]string, 0, len(r.Clouds))
+		for name := range r.Clouds {
+			clouds = append(clouds, name)
+		}
+		sort.Strings(clouds)
+		for _, name := range clouds {
+			c := r.Clouds[name]
+			for _, port := range c.TCPPorts {
+				addrs = append(addrs, fmt.Sprintf("%s:%d", c.Host, port))
+			}
+		}
+	}
+	return addrs
+}
@@ -0,0 +1,138 @@
+// Package fingerprint recognizes the security products that sit between
+// nethelp and Sauce Labs from response headers, block page bodies and the
+// issuers of intercepted TLS certificates.
+package fingerprint
+
+import (
+	_ "embed" // needed for the vendor database
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// vendorsJSON is the fingerprint database.  Add new products to
+// vendors.json, every pattern is a Go regular expression.
+//
+//go:embed vendors.json
+var vendorsJSON []byte
+
+// Vendor is a proxy, firewall or DNS filter nethelp can recognize
+type Vendor struct {
+	Name    string            `json:"name"`
+	Team    string            `json:"team"`
+	Headers map[string]string `json:"headers"`
+	// Block matches only the block pages of the product, Mentions
+	// matches its name, which any page may contain
+	Block    []string `json:"block"`
+	Mentions []string `json:"mentions"`
+	Issuers  []string `json:"issuers"`
+
+	headers  map[string]*regexp.Regexp
+	block    []*regexp.Regexp
+	mentions []*regexp.Regexp
+	issuers  []*regexp.Regexp
+}
+
+var vendors []Vendor
+
+func init() {
+	if err := json.Unmarshal(vendorsJSON, &vendors); err != nil {
+		log.Fatal("The embedded fingerprint database is broken. ", err)
+	}
+	for i := range vendors {
+		v := &vendors[i]
+		v.headers = make(map[string]*regexp.Regexp)
+		for h, p := range v.Headers {
+			v.headers[http.CanonicalHeaderKey(h)] = regexp.MustCompile(p)
+		}
+		for _, p := range v.Block {
+			v.block = append(v.block, regexp.MustCompile(p))
+		}
+		for _, p := range v.Mentions {
+			v.mentions = append(v.mentions, regexp.MustCompile(p))
+		}
+		for _, p := range v.Issuers {
+			v.issuers = append(v.issuers, regexp.MustCompile(p))
+		}
+	}
+}
+
+// Vendors lists the products in the database
+func Vendors() []Vendor {
+	return vendors
+}
+
+// TeamOf names who to talk to about a vendor's product
+func TeamOf(vendor string) string {
+	for _, v := range vendors {
+		if v.Name == vendor {
+			return v.Team
+		}
+	}
+	return "your network team"
+}
+
+// Match is a recognized product.  BlockPage is set when the body of the
+// response came from the product instead of the server that was asked.
+type Match struct {
+	Vendor    string
+	Team      string
+	BlockPage bool
+	Evidence  string
+}
+
+// Identify looks for a known product in resp and the first bytes of its
+// body.  A block page marker in the body means the product answered with
+// its own page.  A product name in the body only counts as a block page
+// when the answer is an error or a redirect, a 200 page may just mention
+// the product.  A header or certificate issuer match only means the
+// traffic goes through it.
+func Identify(resp *http.Response, body []byte, issuer string) (Match, bool) {
+	// block pages are HTML or plain text, never the JSON of an API
+	if strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		body = nil
+	}
+	redirected := resp.Request != nil && resp.Request.Response != nil
+	answered := resp.StatusCode/100 == 2 && !redirected
+	if m, ok := matchBody(body, func(v *Vendor) []*regexp.Regexp { return v.block }); ok {
+		m.BlockPage = true
+		return m, true
+	}
+	mention, mentioned := matchBody(body, func(v *Vendor) []*regexp.Regexp { return v.mentions })
+	if mentioned && !answered {
+		mention.BlockPage = true
+		return mention, true
+	}
+	for _, v := range vendors {
+		for h, re := range v.headers {
+			for _, value := range resp.Header[h] {
+				if re.MatchString(value) {
+					return Match{Vendor: v.Name, Team: v.Team, Evidence: h + ": " + value}, true
+				}
+			}
+		}
+		for _, re := range v.issuers {
+			if issuer != "" && re.MatchString(issuer) {
+				return Match{Vendor: v.Name, Team: v.Team, Evidence: "certificate issued by " + issuer}, true
+			}
+		}
+	}
+	return mention, mentioned
+}
+
+// matchBody returns the first vendor with one of the patterns picked from
+// it in body
+func matchBody(body []byte, patterns func(v *Vendor) []*regexp.Regexp) (Match, bool) {
+	for i := range vendors {
+		v := &vendors[i]
+		for _, re := range patterns(v) {
+			if loc := re.FindIndex(body); loc != nil {
+				return Match{Vendor: v.Name, Team: v.Team, Evidence: "body contains " + string(body[loc[0]:loc[1]])}, true
+			}
+		}
+	}
+	return Match{}, false
+}
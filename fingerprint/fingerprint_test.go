@@ -0,0 +1,98 @@
+package fingerprint
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestIdentify(t *testing.T) {
+	redirect := &http.Request{URL: &url.URL{Scheme: "https", Host: "ondemand.saucelabs.com", Path: "/"}}
+	tests := []struct {
+		name          string
+		status        int
+		header        http.Header
+		body          string
+		issuer        string
+		redirected    bool
+		wantVendor    string
+		wantBlockPage bool
+	}{
+		{
+			name:          "Zscaler block page served with 200",
+			status:        200,
+			body:          `<a href="https://gateway.zscaler.net/policy">Website blocked</a>`,
+			wantVendor:    "Zscaler",
+			wantBlockPage: true,
+		},
+		{
+			name:       "200 page that mentions a vendor",
+			status:     200,
+			body:       "<p>We moved our office network to Zscaler last spring.</p>",
+			wantVendor: "Zscaler",
+		},
+		{
+			name:          "vendor name on a 403",
+			status:        403,
+			body:          "<h1>Access denied by Fortinet</h1>",
+			wantVendor:    "Fortinet FortiGate",
+			wantBlockPage: true,
+		},
+		{
+			name:          "vendor name after a redirect",
+			status:        200,
+			body:          "<h1>Cisco Umbrella</h1>",
+			redirected:    true,
+			wantVendor:    "Cisco Umbrella",
+			wantBlockPage: true,
+		},
+		{
+			name:          "Squid error page",
+			status:        200,
+			body:          "<p>ERR_ACCESS_DENIED</p>",
+			wantVendor:    "Squid",
+			wantBlockPage: true,
+		},
+		{
+			name:       "header beats a mention on a 200 page",
+			status:     200,
+			header:     http.Header{"Via": {"1.1 proxy (squid/4.10)"}},
+			body:       "<p>Our Fortinet firewall</p>",
+			wantVendor: "Squid",
+		},
+		{
+			name:   "JSON is never a block page",
+			status: 403,
+			header: http.Header{"Content-Type": {"application/json"}},
+			body:   `{"message": "blocked by Zscaler at gateway.zscaler.net"}`,
+		},
+		{
+			name:       "intercepted certificate",
+			status:     200,
+			issuer:     "CN=Zscaler Intermediate Root CA",
+			wantVendor: "Zscaler",
+		},
+		{
+			name:   "nothing known",
+			status: 200,
+			body:   "<h1>Sauce Labs</h1>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := tt.header
+			if header == nil {
+				header = http.Header{}
+			}
+			req := &http.Request{URL: redirect.URL}
+			if tt.redirected {
+				req.Response = &http.Response{StatusCode: 302, Request: redirect}
+			}
+			resp := &http.Response{StatusCode: tt.status, Header: header, Request: req}
+			m, ok := Identify(resp, []byte(tt.body), tt.issuer)
+			if ok != (tt.wantVendor != "") || m.Vendor != tt.wantVendor || m.BlockPage != tt.wantBlockPage {
+				t.Errorf("Identify() = %+v, %v, want %q with block page %v", m, ok, tt.wantVendor, tt.wantBlockPage)
+			}
+		})
+	}
+}
@@ -4,8 +4,11 @@ require (
 	github.com/mitchellh/go-homedir v1.1.0
 	github.com/sirupsen/logrus v1.8.1
 	github.com/spf13/cobra v0.0.7
+	github.com/spf13/pflag v1.0.3
 	github.com/spf13/viper v1.7.1
 	golang.org/x/net v0.0.0-20190620200207-3b0461eec859
+	golang.org/x/term v0.0.0-20201210144234-2321bbc49cbf
+	gopkg.in/yaml.v2 v2.2.4
 )
 
-go 1.13
+go 1.16
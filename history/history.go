@@ -0,0 +1,122 @@
+// Package history keeps the snapshot of every run in the nethelp config
+// dir, so trends on one host can be followed across days.
+package history
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mdsauce/nethelp/config"
+	"github.com/mdsauce/nethelp/snapshot"
+)
+
+// Keep is how many runs the store holds before the oldest are removed
+const Keep = 500
+
+// idFormat names runs by the time they finished, which also sorts them
+const idFormat = "20060102-150405"
+
+// Dir is where runs are stored, history/ in the nethelp config dir
+func Dir() (string, error) {
+	dir, err := config.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history"), nil
+}
+
+// Record stores a run and removes the oldest runs beyond Keep.
+// Returns the ID of the run.
+func Record(s snapshot.Snapshot) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	id := s.Taken.Format(idFormat)
+	for n := 2; exists(filepath.Join(dir, id+".json")); n++ {
+		id = fmt.Sprintf("%s-%d", s.Taken.Format(idFormat), n)
+	}
+	if err := snapshot.Save(filepath.Join(dir, id+".json"), s); err != nil {
+		return "", err
+	}
+	ids, err := IDs()
+	if err != nil {
+		return id, err
+	}
+	for len(ids) > Keep {
+		os.Remove(filepath.Join(dir, ids[0]+".json"))
+		ids = ids[1:]
+	}
+	return id, nil
+}
+
+// IDs lists the stored runs, oldest first
+func IDs() ([]string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, f := range files {
+		if !f.IsDir() && strings.HasSuffix(f.Name(), ".json") {
+			ids = append(ids, strings.TrimSuffix(f.Name(), ".json"))
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// Load reads one run.  "last" is the most recent run.
+func Load(id string) (snapshot.Snapshot, error) {
+	dir, err := Dir()
+	if err != nil {
+		return snapshot.Snapshot{}, err
+	}
+	if id == "last" {
+		ids, err := IDs()
+		if err != nil {
+			return snapshot.Snapshot{}, err
+		}
+		if len(ids) == 0 {
+			return snapshot.Snapshot{}, fmt.Errorf("no runs recorded in %s yet", dir)
+		}
+		id = ids[len(ids)-1]
+	}
+	return snapshot.Load(filepath.Join(dir, id+".json"))
+}
+
+// Last loads the most recent n runs, oldest first
+func Last(n int) ([]string, []snapshot.Snapshot, error) {
+	ids, err := IDs()
+	if err != nil {
+		return nil, nil, err
+	}
+	if n > 0 && len(ids) > n {
+		ids = ids[len(ids)-n:]
+	}
+	runs := make([]snapshot.Snapshot, 0, len(ids))
+	for _, id := range ids {
+		s, err := Load(id)
+		if err != nil {
+			return nil, nil, fmt.Errorf("run %s: %v", id, err)
+		}
+		runs = append(runs, s)
+	}
+	return ids, runs, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,56 @@
+package history
+
+import (
+	"sort"
+
+	"github.com/mdsauce/nethelp/connections"
+	"github.com/mdsauce/nethelp/snapshot"
+)
+
+// Row is the availability of one check across runs
+type Row struct {
+	Key string
+	// Outcomes holds one entry per run, empty when the check did not run
+	Outcomes []connections.Outcome
+}
+
+// Availability is the share of runs that reached the endpoint, counting
+// only runs the check was part of.  Rejected credentials still reached it.
+func (r Row) Availability() float64 {
+	ran, up := 0, 0
+	for _, o := range r.Outcomes {
+		if o == "" {
+			continue
+		}
+		ran++
+		if o != connections.Failed {
+			up++
+		}
+	}
+	if ran == 0 {
+		return 0
+	}
+	return float64(up) / float64(ran)
+}
+
+// Timeline lines up every check of runs, which must be oldest first
+func Timeline(runs []snapshot.Snapshot) []Row {
+	rows := make(map[string]*Row)
+	for i, run := range runs {
+		for _, r := range run.Results {
+			key := snapshot.Key(r)
+			row, ok := rows[key]
+			if !ok {
+				row = &Row{Key: key, Outcomes: make([]connections.Outcome, len(runs))}
+				rows[key] = row
+			}
+			row.Outcomes[i] = r.Outcome
+		}
+	}
+	timeline := make([]Row, 0, len(rows))
+	for _, row := range rows {
+		timeline = append(timeline, *row)
+	}
+	sort.Slice(timeline, func(i, j int) bool { return timeline[i].Key < timeline[j].Key })
+	return timeline
+}
@@ -0,0 +1,55 @@
+package idle
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/mdsauce/nethelp/ports"
+)
+
+// EchoPorts listens on every port in the list and greets each connection
+// with ports.Banner, so 'nethelp ports' can prove it reached this host.
+// Ports that cannot be bound, e.g. ones in use or below 1024 without root,
+// are counted and skipped.  Blocks while any listener is open.
+func EchoPorts(list []int) error {
+	var listeners []net.Listener
+	var failed []int
+	for _, port := range list {
+		l, err := net.Listen("tcp", ":"+strconv.Itoa(port))
+		if err != nil {
+			failed = append(failed, port)
+			continue
+		}
+		listeners = append(listeners, l)
+	}
+	if len(failed) > 0 {
+		fmt.Printf("Could not listen on %d port(s): %s\n", len(failed), ports.Ranges(failed))
+	}
+	if len(listeners) == 0 {
+		return fmt.Errorf("could not listen on any of the %d port(s)", len(list))
+	}
+	fmt.Printf("Echoing on %d port(s)\n", len(listeners))
+	done := make(chan error)
+	for _, l := range listeners {
+		go func(l net.Listener) {
+			port := l.Addr().(*net.TCPAddr).Port
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					done <- err
+					return
+				}
+				fmt.Printf("connection on port %d from %s\n", port, conn.RemoteAddr())
+				conn.Write([]byte(ports.Banner(port)))
+				conn.Close()
+			}
+		}(l)
+	}
+	for range listeners {
+		if err := <-done; err != nil {
+			fmt.Println(err)
+		}
+	}
+	return nil
+}
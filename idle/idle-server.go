@@ -6,10 +6,24 @@ import (
 	"os"
 	"strconv"
 	"time"
+
+	"github.com/mdsauce/nethelp/echo"
+	"github.com/mdsauce/nethelp/payload"
 )
 
 func handler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case echo.Path:
+			echo.Handler().ServeHTTP(w, r)
+			return
+		case echo.WebSocketPath:
+			echo.WebSocket().ServeHTTP(w, r)
+			return
+		case payload.Path:
+			payload.Handler().ServeHTTP(w, r)
+			return
+		}
 		i, err := strconv.ParseInt(r.URL.Path[1:], 10, 32)
 		if err != nil {
 			fmt.Println(err)
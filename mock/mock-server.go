@@ -0,0 +1,229 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mdsauce/nethelp/echo"
+	"github.com/mdsauce/nethelp/payload"
+)
+
+// Config controls how the mock Sauce Labs server answers requests
+type Config struct {
+	Port       string
+	Username   string
+	AccessKey  string
+	Latency    time.Duration
+	Jitter     time.Duration
+	FailRate   float64
+	FailStatus int
+	// Portal redirects every request to this login page, like a captive portal
+	Portal string
+	// ClockOffset moves the Date header of every response, like a server with a wrong clock
+	ClockOffset time.Duration
+}
+
+// authorized checks basic auth against the configured credentials.
+// An empty Username disables authentication entirely.
+func (cfg Config) authorized(r *http.Request) bool {
+	if cfg.Username == "" {
+		return true
+	}
+	user, key, ok := r.BasicAuth()
+	return ok && user == cfg.Username && key == cfg.AccessKey
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="nethelp mock"`)
+	writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Not authorized"})
+}
+
+func statusHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status": 0,
+		"value": map[string]interface{}{
+			"ready":   true,
+			"message": "nethelp mock server is ready",
+			"build":   map[string]string{"version": "mock"},
+		},
+	})
+}
+
+// sessionHandler answers new session, get URL and delete session commands.
+// Sessions only live in memory.
+func sessionHandler(cfg Config) http.HandlerFunc {
+	var mu sync.Mutex
+	sessions := make(map[string]bool)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !cfg.authorized(r) {
+			unauthorized(w)
+			return
+		}
+		parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/wd/hub/session"), "/"), "/")
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.Method == http.MethodPost && parts[0] == "":
+			var body struct {
+				Capabilities map[string]interface{} `json:"capabilities"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Capabilities == nil {
+				writeJSON(w, http.StatusBadRequest, wdError("invalid argument", "missing W3C capabilities"))
+				return
+			}
+			id := fmt.Sprintf("%016x", rand.Int63())
+			sessions[id] = true
+			writeJSON(w, http.StatusOK, map[string]interface{}{
+				"value": map[string]interface{}{"sessionId": id, "capabilities": body.Capabilities},
+			})
+		case !sessions[parts[0]]:
+			writeJSON(w, http.StatusNotFound, wdError("invalid session id", "no such session "+parts[0]))
+		case r.Method == http.MethodGet && len(parts) == 2 && parts[1] == "url":
+			writeJSON(w, http.StatusOK, map[string]interface{}{"value": "about:blank"})
+		case r.Method == http.MethodDelete && len(parts) == 1:
+			delete(sessions, parts[0])
+			writeJSON(w, http.StatusOK, map[string]interface{}{"value": nil})
+		default:
+			writeJSON(w, http.StatusNotFound, wdError("unknown command", r.Method+" "+r.URL.Path))
+		}
+	}
+}
+
+func wdError(code, message string) map[string]interface{} {
+	return map[string]interface{}{"value": map[string]string{"error": code, "message": message}}
+}
+
+// restHandler serves /rest/v1/users/{user} and /rest/v1/{user}/tunnels
+func restHandler(cfg Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		// parts[0:2] is always "rest", "v1"
+		if len(parts) != 4 {
+			http.NotFound(w, r)
+			return
+		}
+		var user string
+		var body interface{}
+		switch {
+		case parts[2] == "users":
+			user = parts[3]
+			body = map[string]interface{}{
+				"id":       user,
+				"username": user,
+				"concurrency_limit": map[string]int{
+					"overall":     10,
+					"mac":         5,
+					"real_device": 2,
+				},
+			}
+		case parts[3] == "tunnels":
+			user = parts[2]
+			body = []string{}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		if !cfg.authorized(r) {
+			unauthorized(w)
+			return
+		}
+		if cfg.Username != "" && user != cfg.Username {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": fmt.Sprintf("User %s not found", user)})
+			return
+		}
+		writeJSON(w, http.StatusOK, body)
+	}
+}
+
+// noContentHandler answers the captive portal probe like the open internet
+func noContentHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func rootHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	fmt.Fprintln(w, "nethelp mock of ondemand.saucelabs.com")
+}
+
+// inject delays every request and fails a share of them.  A FailStatus of 0
+// drops the connection without answering, like a reset from a firewall.
+// With a Portal every request that gets through is sent to the login page.
+func inject(cfg Config, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Printf("%s %s from %s\n", r.Method, r.URL.Path, r.RemoteAddr)
+		delay := cfg.Latency
+		if cfg.Jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(cfg.Jitter)))
+		}
+		time.Sleep(delay)
+		if cfg.ClockOffset != 0 {
+			w.Header().Set("Date", time.Now().Add(cfg.ClockOffset).UTC().Format(http.TimeFormat))
+		}
+
+		if cfg.FailRate > 0 && rand.Float64() < cfg.FailRate {
+			if cfg.FailStatus == 0 {
+				if hj, ok := w.(http.Hijacker); ok {
+					conn, _, err := hj.Hijack()
+					if err == nil {
+						fmt.Printf("dropped connection for %s\n", r.URL.Path)
+						conn.Close()
+						return
+					}
+				}
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Printf("injected HTTP %d for %s\n", cfg.FailStatus, r.URL.Path)
+			http.Error(w, http.StatusText(cfg.FailStatus), cfg.FailStatus)
+			return
+		}
+		if cfg.Portal != "" {
+			http.Redirect(w, r, cfg.Portal, http.StatusFound)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Handler builds the routes that mirror the Sauce Labs endpoints nethelp targets
+func Handler(cfg Config) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", rootHandler)
+	mux.HandleFunc("/generate_204", noContentHandler)
+	mux.Handle(echo.Path, echo.Handler())
+	mux.Handle(echo.WebSocketPath, echo.WebSocket())
+	mux.Handle(payload.Path, payload.Handler())
+	mux.HandleFunc("/wd/hub/status", statusHandler)
+	session := sessionHandler(cfg)
+	mux.HandleFunc("/wd/hub/session", session)
+	mux.HandleFunc("/wd/hub/session/", session)
+	mux.HandleFunc("/rest/v1/", restHandler(cfg))
+	return inject(cfg, mux)
+}
+
+// MockServer starts a webserver that stands in for saucelabs.com so
+// nethelp can be exercised without network access
+func MockServer(cfg Config) {
+	rand.Seed(time.Now().UnixNano())
+	s := &http.Server{
+		Addr:    fmt.Sprint(":", cfg.Port),
+		Handler: Handler(cfg),
+	}
+
+	fmt.Println("Starting mock Sauce Labs server, listening on port ", cfg.Port)
+	fmt.Println(s.ListenAndServe())
+}
@@ -0,0 +1,80 @@
+// Package payload serves a large download whose every byte the client can
+// predict, so a proxy that truncates or re-encodes it is caught.
+package payload
+
+import (
+	"compress/gzip"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Path is where the mock and the idle server serve the payload
+const Path = "/payload"
+
+// DefaultSize is about the size of a screenshot as WebDriver returns it
+const DefaultSize = 5 << 20
+
+// maxSize keeps a typo from making the server build gigabytes
+const maxSize = 100 << 20
+
+const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// Generate returns size bytes shaped like a WebDriver screenshot response,
+// a JSON value of base64 text.  The same size always gives the same bytes.
+func Generate(size int) []byte {
+	prefix, suffix := `{"value":"`, `"}`
+	if size < len(prefix)+len(suffix) {
+		prefix, suffix = "", ""
+	}
+	b := make([]byte, size)
+	copy(b, prefix)
+	copy(b[size-len(suffix):], suffix)
+	rnd := rand.New(rand.NewSource(int64(size)))
+	for i := len(prefix); i < size-len(suffix); i++ {
+		b[i] = alphabet[rnd.Intn(len(alphabet))]
+	}
+	return b
+}
+
+// SizeOf returns the size a payload URL asks for.  ok is false for URLs
+// that are not a nethelp payload.
+func SizeOf(raw string) (int, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || !strings.HasSuffix(u.Path, Path) {
+		return 0, false
+	}
+	size := DefaultSize
+	if s := u.Query().Get("size"); s != "" {
+		if size, err = strconv.Atoi(s); err != nil || size < 0 || size > maxSize {
+			return 0, false
+		}
+	}
+	return size, true
+}
+
+// Handler serves Generate(size) for /payload?size=N, gzipped when the client accepts it
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		size, ok := SizeOf(r.URL.String())
+		if !ok {
+			http.Error(w, fmt.Sprintf("size must be a number of bytes up to %d", maxSize), http.StatusBadRequest)
+			return
+		}
+		body := Generate(size)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Vary", "Accept-Encoding")
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			w.Write(body)
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write(body)
+		gz.Close()
+	})
+}
@@ -0,0 +1,206 @@
+// Package ports sweeps a range of TCP ports on one target to find out which
+// ports a firewall lets out.  The target is best a host running
+// 'nethelp idle --ports', which listens on every port and greets with Banner.
+package ports
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// State is what a sweep learned about one port
+type State string
+
+// Every port ends in one of these
+const (
+	// Open accepted the connection
+	Open State = "open"
+	// Closed was refused, the packets got out but nothing listens on the target
+	Closed State = "closed"
+	// Filtered timed out or was reset, usually a firewall dropping the packets
+	Filtered State = "filtered"
+)
+
+// Banner is the greeting the echo target sends on every port, so a sweep can
+// tell the target from a middlebox that accepts every connection
+func Banner(port int) string {
+	return fmt.Sprintf("nethelp %d\n", port)
+}
+
+// Result is the outcome of one port
+type Result struct {
+	Port     int
+	State    State
+	Duration time.Duration
+	// Echo is set when the target answered with the nethelp banner of this port
+	Echo  bool
+	Error string
+}
+
+// Options controls a sweep
+type Options struct {
+	Target string
+	Ports  []int
+	// Concurrency is how many connections are open at the same time
+	Concurrency int
+	// Rate limits new connections per second, 0 means no limit
+	Rate int
+	// Timeout bounds every connection attempt
+	Timeout time.Duration
+	// Dial opens the connections, nil dials directly
+	Dial func(ctx context.Context, network, addr string) (net.Conn, error)
+	// Progress is called after every port, if set
+	Progress func(done, total int)
+}
+
+// Parse reads a port list like 80,443,8000-8100
+func Parse(spec string) ([]int, error) {
+	seen := make(map[int]bool)
+	var list []int
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		bounds := strings.SplitN(part, "-", 2)
+		lo, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a port or range", part)
+		}
+		hi := lo
+		if len(bounds) == 2 {
+			if hi, err = strconv.Atoi(strings.TrimSpace(bounds[1])); err != nil {
+				return nil, fmt.Errorf("%q is not a port or range", part)
+			}
+		}
+		if lo < 1 || hi > 65535 || lo > hi {
+			return nil, fmt.Errorf("%q is not within 1-65535", part)
+		}
+		for p := lo; p <= hi; p++ {
+			if !seen[p] {
+				seen[p] = true
+				list = append(list, p)
+			}
+		}
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("no ports in %q", spec)
+	}
+	sort.Ints(list)
+	return list, nil
+}
+
+// Sweep connects to every port of the target and returns the results
+// sorted by port.  It stops early when ctx is done.
+func Sweep(ctx context.Context, opts Options) []Result {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 3 * time.Second
+	}
+	if opts.Dial == nil {
+		opts.Dial = (&net.Dialer{}).DialContext
+	}
+	var tick <-chan time.Time
+	if opts.Rate > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(opts.Rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	jobs := make(chan int)
+	results := make([]Result, 0, len(opts.Ports))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for port := range jobs {
+				r := probe(ctx, opts, port)
+				mu.Lock()
+				results = append(results, r)
+				done := len(results)
+				mu.Unlock()
+				if opts.Progress != nil {
+					opts.Progress(done, len(opts.Ports))
+				}
+			}
+		}()
+	}
+feed:
+	for _, port := range opts.Ports {
+		if tick != nil {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		select {
+		case jobs <- port:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	sort.Slice(results, func(i, j int) bool { return results[i].Port < results[j].Port })
+	return results
+}
+
+// bannerWait is how long an open port gets to send the banner.  Servers
+// that wait for the client to speak first, like HTTP, never send one.
+const bannerWait = time.Second
+
+// probe connects to one port and reads the banner, if the target sends one
+func probe(ctx context.Context, opts Options, port int) Result {
+	r := Result{Port: port}
+	dial, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	start := time.Now()
+	conn, err := opts.Dial(dial, "tcp", net.JoinHostPort(opts.Target, strconv.Itoa(port)))
+	r.Duration = time.Since(start)
+	if err != nil {
+		r.Error = err.Error()
+		r.State = Filtered
+		// a proxy turning the tunnel down filters, only the target refuses
+		if strings.Contains(r.Error, "connection refused") || strings.Contains(r.Error, "actively refused") {
+			r.State = Closed
+		}
+		return r
+	}
+	defer conn.Close()
+	r.State = Open
+	conn.SetReadDeadline(time.Now().Add(bannerWait))
+	if line, err := bufio.NewReader(conn).ReadString('\n'); err == nil && line == Banner(port) {
+		r.Echo = true
+	}
+	return r
+}
+
+// Ranges compresses a sorted port list into 80,443,8000-8100
+func Ranges(list []int) string {
+	var parts []string
+	for i := 0; i < len(list); {
+		j := i
+		for j+1 < len(list) && list[j+1] == list[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, strconv.Itoa(list[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", list[i], list[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
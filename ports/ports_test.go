@@ -0,0 +1,115 @@
+package ports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []int
+		wantErr bool
+	}{
+		{spec: "443", want: []int{443}},
+		{spec: "80,443,8000-8003", want: []int{80, 443, 8000, 8001, 8002, 8003}},
+		{spec: " 443 , 80 ,, 80-81 ", want: []int{80, 81, 443}},
+		{spec: "65535", want: []int{65535}},
+		{spec: "", wantErr: true},
+		{spec: "0-10", wantErr: true},
+		{spec: "1-65536", wantErr: true},
+		{spec: "100-90", wantErr: true},
+		{spec: "http", wantErr: true},
+		{spec: "80-", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := Parse(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, want error %v", tt.spec, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %v, want %v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRanges(t *testing.T) {
+	tests := []struct {
+		list []int
+		want string
+	}{
+		{nil, ""},
+		{[]int{443}, "443"},
+		{[]int{80, 443, 8000, 8001, 8002}, "80,443,8000-8002"},
+		{[]int{1, 2, 4, 5, 7}, "1-2,4-5,7"},
+	}
+	for _, tt := range tests {
+		if got := Ranges(tt.list); got != tt.want {
+			t.Errorf("Ranges(%v) = %q, want %q", tt.list, got, tt.want)
+		}
+	}
+}
+
+func TestRangesReversesParse(t *testing.T) {
+	const spec = "22,80,443,8000-8100"
+	list, err := Parse(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Ranges(list); got != spec {
+		t.Errorf("Ranges(Parse(%q)) = %q", spec, got)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	open, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer open.Close()
+	openPort := open.Addr().(*net.TCPAddr).Port
+	go func() {
+		for {
+			conn, err := open.Accept()
+			if err != nil {
+				return
+			}
+			fmt.Fprint(conn, Banner(openPort))
+			conn.Close()
+		}
+	}()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedPort := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+
+	results := Sweep(context.Background(), Options{Target: "127.0.0.1", Ports: []int{openPort, closedPort}, Concurrency: 2})
+	states := make(map[int]State)
+	for _, r := range results {
+		states[r.Port] = r.State
+		if r.Port == openPort && !r.Echo {
+			t.Errorf("port %d sent the banner but Echo is not set", r.Port)
+		}
+	}
+	if states[openPort] != Open || states[closedPort] != Closed {
+		t.Errorf("Sweep() states = %v, want %d open and %d closed", states, openPort, closedPort)
+	}
+}
+
+func TestSweepProxyRefusalIsFiltered(t *testing.T) {
+	refuse := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, errors.New("SOCKS4 proxy refused " + addr + ": request rejected or failed")
+	}
+	results := Sweep(context.Background(), Options{Target: "echo.example.com", Ports: []int{25}, Dial: refuse})
+	if len(results) != 1 || results[0].State != Filtered {
+		t.Errorf("Sweep() = %+v, want port 25 filtered", results)
+	}
+}
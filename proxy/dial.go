@@ -0,0 +1,316 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	netproxy "golang.org/x/net/proxy"
+)
+
+// Schemes are the proxy URL schemes nethelp can tunnel through
+var Schemes = []string{"http", "https", "socks4", "socks4a", "socks5", "socks5h"}
+
+// supported tells whether scheme is one of Schemes
+func supported(scheme string) bool {
+	for _, s := range Schemes {
+		if s == scheme {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoteDNS tells whether the proxy resolves the host names of the
+// targets.  HTTP proxies, socks5h and socks4a do; socks5 and socks4 get an
+// address nethelp resolved locally.
+func RemoteDNS(proxyURL *url.URL) bool {
+	switch strings.ToLower(proxyURL.Scheme) {
+	case "socks5", "socks4":
+		return false
+	}
+	return true
+}
+
+// Describe names the scheme and the DNS mode of proxyURL for output
+func Describe(proxyURL *url.URL) string {
+	if proxyURL == nil {
+		return "direct"
+	}
+	dns := "local DNS"
+	if RemoteDNS(proxyURL) {
+		dns = "remote DNS"
+	}
+	return strings.ToLower(proxyURL.Scheme) + ", " + dns
+}
+
+// RefusedError is a proxy turning a tunnel down.  Auth tells whether it
+// wanted other credentials.
+type RefusedError struct {
+	Reason string
+	Auth   bool
+}
+
+func (e *RefusedError) Error() string {
+	return e.Reason
+}
+
+// DialFunc opens one connection, like net.Dialer.DialContext
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// Dialer returns a DialFunc that tunnels every connection through
+// proxyURL.  forward opens the connection to the proxy itself.  HTTP and
+// HTTPS proxies tunnel with CONNECT, SOCKS proxies with their handshake,
+// and the username and password of the URL authenticate either way.
+func Dialer(proxyURL *url.URL, forward DialFunc) (DialFunc, error) {
+	scheme := strings.ToLower(proxyURL.Scheme)
+	if !supported(scheme) {
+		return nil, fmt.Errorf("proxy scheme %q is not supported, use one of %s", proxyURL.Scheme, strings.Join(Schemes, ", "))
+	}
+	server := proxyAddr(proxyURL)
+	switch scheme {
+	case "http", "https":
+		return func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return connect(ctx, proxyURL, forward, network, server, addr)
+		}, nil
+	case "socks4", "socks4a":
+		return func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return socks4(ctx, proxyURL, forward, network, server, addr)
+		}, nil
+	}
+	var auth *netproxy.Auth
+	if proxyURL.User != nil {
+		auth = &netproxy.Auth{User: proxyURL.User.Username()}
+		auth.Password, _ = proxyURL.User.Password()
+	}
+	d, err := netproxy.SOCKS5("tcp", server, auth, forwarder(forward))
+	if err != nil {
+		return nil, err
+	}
+	socks := d.(netproxy.ContextDialer)
+	local := scheme == "socks5"
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if local {
+			resolved, err := resolve(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			addr = resolved
+		}
+		conn, err := socks.DialContext(ctx, network, addr)
+		return conn, socks5Refusal(err)
+	}, nil
+}
+
+// socks5Refusal turns a login or ruleset refusal of a SOCKS5 proxy into a
+// RefusedError.  x/net/proxy reports the handshake answers only as text.
+func socks5Refusal(err error) error {
+	var op *net.OpError
+	if !errors.As(err, &op) || op.Err == nil {
+		return err
+	}
+	switch msg := op.Err.Error(); {
+	case strings.Contains(msg, "username/password"), msg == "no acceptable authentication methods":
+		return &RefusedError{Reason: "SOCKS5 proxy rejected the login: " + msg, Auth: true}
+	case strings.HasSuffix(msg, "general SOCKS server failure"), strings.HasSuffix(msg, "connection not allowed by ruleset"):
+		return &RefusedError{Reason: fmt.Sprintf("SOCKS5 proxy refused %s: %s", op.Addr, strings.TrimPrefix(msg, "unknown error "))}
+	}
+	return err
+}
+
+// proxyAddr is host:port of the proxy with the default port of its scheme
+func proxyAddr(proxyURL *url.URL) string {
+	port := proxyURL.Port()
+	if port == "" {
+		port = defaultPorts[strings.ToLower(proxyURL.Scheme)]
+	}
+	return net.JoinHostPort(proxyURL.Hostname(), port)
+}
+
+// forwarder adapts a DialFunc to the dialer x/net/proxy forwards through
+type forwarder DialFunc
+
+func (f forwarder) Dial(network, addr string) (net.Conn, error) {
+	return f(context.Background(), network, addr)
+}
+
+func (f forwarder) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return f(ctx, network, addr)
+}
+
+// resolve replaces the host of addr with one of its addresses, an IPv4
+// address for tcp4
+func resolve(ctx context.Context, network, addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	if net.ParseIP(host) != nil {
+		return addr, nil
+	}
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	for _, ip := range ips {
+		if network != "tcp4" || ip.IP.To4() != nil {
+			return net.JoinHostPort(ip.IP.String(), port), nil
+		}
+	}
+	return "", &net.DNSError{Err: "no IPv4 address", Name: host}
+}
+
+// connect opens a CONNECT tunnel to addr through an HTTP proxy, or an
+// HTTPS proxy after a TLS handshake with the proxy itself
+func connect(ctx context.Context, proxyURL *url.URL, forward DialFunc, network, server, addr string) (net.Conn, error) {
+	conn, err := forward(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	if strings.EqualFold(proxyURL.Scheme, "https") {
+		// like the HTTP checks through a proxy, the proxy certificate is not verified
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname(), InsecureSkipVerify: true})
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("TLS handshake with the proxy: %v", err)
+		}
+		conn = tlsConn
+	}
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy answered CONNECT with %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, &RefusedError{
+			Reason: fmt.Sprintf("proxy answered CONNECT %s with %s", addr, strings.ToLower(resp.Status)),
+			Auth:   resp.StatusCode == http.StatusProxyAuthRequired,
+		}
+	}
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn hands out what the reader buffered past the CONNECT answer first
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// socks4Replies explains the SOCKS4 reply codes that refuse a connection
+var socks4Replies = map[byte]string{
+	91: "request rejected or failed",
+	92: "the proxy could not reach identd on the client",
+	93: "identd on the client reported a different user",
+}
+
+// socks4 opens a connection to addr through a SOCKS4 proxy.  socks4a
+// sends the host name along, socks4 an IPv4 address resolved locally.
+// The username of the URL is the SOCKS4 user id.
+func socks4(ctx context.Context, proxyURL *url.URL, forward DialFunc, network, server, addr string) (net.Conn, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, fmt.Errorf("port %q is not a number", portStr)
+	}
+	ip := net.ParseIP(host).To4()
+	remote := ip == nil && strings.EqualFold(proxyURL.Scheme, "socks4a")
+	if ip == nil && !remote {
+		resolved, err := resolve(ctx, "tcp4", addr)
+		if err != nil {
+			return nil, err
+		}
+		h, _, _ := net.SplitHostPort(resolved)
+		ip = net.ParseIP(h).To4()
+	}
+	if remote {
+		// 0.0.0.x asks the proxy to resolve the host name that follows
+		ip = net.IPv4(0, 0, 0, 1).To4()
+	}
+
+	req := []byte{4, 1, 0, 0}
+	binary.BigEndian.PutUint16(req[2:], uint16(port))
+	req = append(req, ip...)
+	if proxyURL.User != nil {
+		req = append(req, proxyURL.User.Username()...)
+	}
+	req = append(req, 0)
+	if remote {
+		req = append(req, host...)
+		req = append(req, 0)
+	}
+
+	conn, err := forward(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	if _, err := conn.Write(req); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	reply := make([]byte, 8)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("no SOCKS4 reply from the proxy: %v", err)
+	}
+	if reply[0] != 0 {
+		conn.Close()
+		return nil, errors.New("the proxy does not speak SOCKS4")
+	}
+	if reply[1] != 90 {
+		conn.Close()
+		reason := socks4Replies[reply[1]]
+		if reason == "" {
+			reason = fmt.Sprintf("reply code %d", reply[1])
+		}
+		// 92 and 93 are about the identd user id, the SOCKS4 login
+		return nil, &RefusedError{Reason: fmt.Sprintf("SOCKS4 proxy refused %s: %s", addr, reason), Auth: reply[1] == 92 || reply[1] == 93}
+	}
+	return conn, nil
+}
@@ -0,0 +1,248 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// socks4Server answers one SOCKS4 request on conn with code and returns
+// the request bytes it read
+func socks4Server(conn net.Conn, code byte) []byte {
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+	req := make([]byte, 8)
+	if _, err := io.ReadFull(br, req); err != nil {
+		return nil
+	}
+	user, err := br.ReadBytes(0)
+	if err != nil {
+		return nil
+	}
+	req = append(req, user...)
+	if bytes.Equal(req[4:7], []byte{0, 0, 0}) && req[7] != 0 {
+		host, err := br.ReadBytes(0)
+		if err != nil {
+			return nil
+		}
+		req = append(req, host...)
+	}
+	conn.Write([]byte{0, code, 0, 0, 0, 0, 0, 0})
+	return req
+}
+
+func TestSocks4Request(t *testing.T) {
+	tests := []struct {
+		name  string
+		proxy string
+		addr  string
+		want  []byte
+	}{
+		{
+			name:  "socks4 with user id",
+			proxy: "socks4://alice@proxy.inc.com:1080",
+			addr:  "10.1.2.3:443",
+			want:  append([]byte{4, 1, 1, 187, 10, 1, 2, 3}, "alice\x00"...),
+		},
+		{
+			name:  "socks4 without user id",
+			proxy: "socks4://proxy.inc.com:1080",
+			addr:  "192.168.0.1:80",
+			want:  []byte{4, 1, 0, 80, 192, 168, 0, 1, 0},
+		},
+		{
+			name:  "socks4a sends the host name",
+			proxy: "socks4a://alice@proxy.inc.com:1080",
+			addr:  "ondemand.saucelabs.com:4444",
+			want:  append([]byte{4, 1, 0x11, 0x5c, 0, 0, 0, 1}, "alice\x00ondemand.saucelabs.com\x00"...),
+		},
+		{
+			name:  "socks4a with an address sends the address",
+			proxy: "socks4a://proxy.inc.com:1080",
+			addr:  "10.1.2.3:443",
+			want:  []byte{4, 1, 1, 187, 10, 1, 2, 3, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxyURL, err := url.Parse(tt.proxy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			client, server := net.Pipe()
+			got := make(chan []byte, 1)
+			go func() { got <- socks4Server(server, 90) }()
+			forward := func(ctx context.Context, network, addr string) (net.Conn, error) {
+				if addr != "proxy.inc.com:1080" {
+					t.Errorf("dialed %s instead of the proxy", addr)
+				}
+				return client, nil
+			}
+			dial, err := Dialer(proxyURL, forward)
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn, err := dial(context.Background(), "tcp", tt.addr)
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			conn.Close()
+			if req := <-got; !bytes.Equal(req, tt.want) {
+				t.Errorf("request = %v, want %v", req, tt.want)
+			}
+		})
+	}
+}
+
+func TestSocks4Refused(t *testing.T) {
+	tests := []struct {
+		code     byte
+		wantAuth bool
+	}{
+		{91, false},
+		{92, true},
+		{93, true},
+	}
+	proxyURL, _ := url.Parse("socks4://alice@proxy.inc.com:1080")
+	for _, tt := range tests {
+		client, server := net.Pipe()
+		go socks4Server(server, tt.code)
+		dial, err := Dialer(proxyURL, func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return client, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = dial(context.Background(), "tcp", "10.1.2.3:443")
+		var refused *RefusedError
+		if !errors.As(err, &refused) || refused.Auth != tt.wantAuth {
+			t.Errorf("reply %d: error = %v, want a RefusedError with Auth %v", tt.code, err, tt.wantAuth)
+		}
+	}
+}
+
+// connectProxy is an HTTP proxy that tunnels CONNECT requests for alice
+// and refuses tunnels to blocked.example.com
+func connectProxy() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "CONNECT" {
+			http.Error(w, "only CONNECT", http.StatusMethodNotAllowed)
+			return
+		}
+		want := "Basic " + base64.StdEncoding.EncodeToString([]byte("alice:s3cret"))
+		if r.Header.Get("Proxy-Authorization") != want {
+			w.Header().Set("Proxy-Authenticate", `Basic realm="proxy"`)
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		if strings.HasPrefix(r.Host, "blocked.example.com:") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		target, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			target.Close()
+			return
+		}
+		rw.WriteString("HTTP/1.1 200 Connection established\r\n\r\n")
+		rw.Flush()
+		go func() {
+			io.Copy(target, conn)
+			target.Close()
+		}()
+		io.Copy(conn, target)
+		conn.Close()
+	}))
+}
+
+func TestConnect(t *testing.T) {
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+	server := connectProxy()
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	tests := []struct {
+		name     string
+		proxy    string
+		addr     string
+		wantErr  bool
+		wantAuth bool
+	}{
+		{name: "tunnel", proxy: "http://alice:s3cret@" + host, addr: echo.Addr().String()},
+		{name: "no credentials", proxy: "http://" + host, addr: echo.Addr().String(), wantErr: true, wantAuth: true},
+		{name: "wrong password", proxy: "http://alice:wrong@" + host, addr: echo.Addr().String(), wantErr: true, wantAuth: true},
+		{name: "blocked target", proxy: "http://alice:s3cret@" + host, addr: "blocked.example.com:443", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxyURL, err := url.Parse(tt.proxy)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dial, err := Dialer(proxyURL, (&net.Dialer{}).DialContext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			conn, err := dial(ctx, "tcp", tt.addr)
+			if tt.wantErr {
+				var refused *RefusedError
+				if !errors.As(err, &refused) || refused.Auth != tt.wantAuth {
+					t.Fatalf("error = %v, want a RefusedError with Auth %v", err, tt.wantAuth)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(5 * time.Second))
+			if _, err := conn.Write([]byte("ping\n")); err != nil {
+				t.Fatal(err)
+			}
+			line, err := bufio.NewReader(conn).ReadString('\n')
+			if err != nil || line != "ping\n" {
+				t.Errorf("echo through the tunnel = %q, %v", line, err)
+			}
+		})
+	}
+}
+
+func TestUnsupportedScheme(t *testing.T) {
+	proxyURL, _ := url.Parse("ftp://proxy.inc.com:21")
+	if _, err := Dialer(proxyURL, (&net.Dialer{}).DialContext); err == nil {
+		t.Error("Dialer accepted an ftp:// proxy")
+	}
+}
@@ -0,0 +1,348 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/mdsauce/nethelp/redact"
+	log "github.com/sirupsen/logrus"
+)
+
+// Setting is one proxy a tool on this machine is configured with
+type Setting struct {
+	// Source is the variable or file the setting was read from
+	Source string
+	// Key names the setting inside the source, e.g. https.proxyHost
+	Key string
+	// Value is the proxy as a URL
+	Value string
+}
+
+// Address returns host:port of the proxy, with the default port of its
+// scheme filled in, so settings written differently compare equal
+func (s Setting) Address() string {
+	return address(s.Value)
+}
+
+func address(raw string) string {
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return raw
+	}
+	port := u.Port()
+	if port == "" {
+		port = defaultPorts[strings.ToLower(u.Scheme)]
+	}
+	return net.JoinHostPort(strings.ToLower(u.Hostname()), port)
+}
+
+var defaultPorts = map[string]string{"http": "80", "https": "443", "socks4": "1080", "socks4a": "1080", "socks5": "1080", "socks5h": "1080"}
+
+// Discover reads the proxy settings of the tools test runners use: the
+// environment, Java system properties, Maven, npm, git, pip, Docker and
+// /etc/environment.  Sources that do not exist are skipped.
+func Discover() []Setting {
+	home, _ := os.UserHomeDir()
+	var found []Setting
+	found = append(found, fromEnv()...)
+	found = append(found, fromJavaOpts()...)
+	found = append(found, fromMaven(filepath.Join(home, ".m2", "settings.xml"))...)
+	found = append(found, fromINI(filepath.Join(home, ".npmrc"), "proxy", "https-proxy", "http-proxy")...)
+	found = append(found, fromINI(filepath.Join(home, ".gitconfig"), "http.proxy", "https.proxy")...)
+	for _, path := range pipConfigs(home) {
+		found = append(found, fromINI(path, "global.proxy")...)
+	}
+	found = append(found, fromDocker(filepath.Join(home, ".docker", "config.json"))...)
+	if runtime.GOOS != "windows" {
+		found = append(found, fromEtcEnvironment("/etc/environment")...)
+	}
+	for i, s := range found {
+		if home != "" && strings.HasPrefix(s.Source, home+string(filepath.Separator)) {
+			found[i].Source = "~" + strings.TrimPrefix(s.Source, home)
+		}
+		if u, err := url.Parse(s.Value); err == nil {
+			if password, ok := u.User.Password(); ok {
+				redact.AddSecret(password)
+			}
+		}
+	}
+	return found
+}
+
+// fromEnv reads the proxy variables of EnvProxies, the lower case ones
+// curl and Python prefer, and PIP_PROXY
+func fromEnv() []Setting {
+	names := append([]string{}, proxyList...)
+	if runtime.GOOS != "windows" {
+		// the environment is case insensitive on Windows
+		names = append(names, "http_proxy", "https_proxy", "all_proxy")
+	}
+	names = append(names, "PIP_PROXY")
+	var found []Setting
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			found = append(found, Setting{Source: "environment", Key: name, Value: v})
+		}
+	}
+	return found
+}
+
+// javaProxies are the system property prefixes of the Java proxy settings
+// and the scheme and default port of each
+var javaProxies = []struct{ prefix, scheme, port string }{
+	{"http.proxy", "http", "80"},
+	{"https.proxy", "http", "443"},
+	{"socksProxy", "socks5", "1080"},
+}
+
+// fromJavaOpts reads -Dhttps.proxyHost and friends from the variables
+// the JVM and most launch scripts pass to Java
+func fromJavaOpts() []Setting {
+	var found []Setting
+	for _, env := range []string{"JAVA_TOOL_OPTIONS", "JAVA_OPTS", "_JAVA_OPTIONS"} {
+		props := make(map[string]string)
+		for _, arg := range strings.Fields(os.Getenv(env)) {
+			if kv := strings.SplitN(strings.TrimPrefix(arg, "-D"), "=", 2); strings.HasPrefix(arg, "-D") && len(kv) == 2 {
+				props[kv[0]] = kv[1]
+			}
+		}
+		for _, p := range javaProxies {
+			host := props[p.prefix+"Host"]
+			if host == "" {
+				continue
+			}
+			port := props[p.prefix+"Port"]
+			if port == "" {
+				port = p.port
+			}
+			found = append(found, Setting{Source: env, Key: p.prefix + "Host", Value: p.scheme + "://" + net.JoinHostPort(host, port)})
+		}
+	}
+	return found
+}
+
+// mavenSettings is the part of ~/.m2/settings.xml that holds proxies
+type mavenSettings struct {
+	Proxies []struct {
+		ID       string `xml:"id"`
+		Active   string `xml:"active"`
+		Protocol string `xml:"protocol"`
+		Host     string `xml:"host"`
+		Port     string `xml:"port"`
+	} `xml:"proxies>proxy"`
+}
+
+// fromMaven reads the active proxies of a Maven settings file
+func fromMaven(path string) []Setting {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var settings mavenSettings
+	if err := xml.Unmarshal(raw, &settings); err != nil {
+		log.WithFields(log.Fields{"file": path, "error": err}).Debug("Could not read the Maven settings.")
+		return nil
+	}
+	var found []Setting
+	for _, p := range settings.Proxies {
+		if p.Host == "" || strings.EqualFold(strings.TrimSpace(p.Active), "false") {
+			continue
+		}
+		scheme := strings.ToLower(strings.TrimSpace(p.Protocol))
+		if scheme == "" {
+			scheme = "http"
+		}
+		port := strings.TrimSpace(p.Port)
+		if port == "" {
+			port = "8080"
+		}
+		found = append(found, Setting{Source: path, Key: "proxy " + p.ID, Value: scheme + "://" + net.JoinHostPort(strings.TrimSpace(p.Host), port)})
+	}
+	return found
+}
+
+// fromINI reads keys from an INI style file like .npmrc, .gitconfig or
+// pip.conf.  Keys inside a section are named section.key, a git
+// subsection like [http "https://host"] becomes http.https://host.key.
+func fromINI(path string, keys ...string) []Setting {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	wanted := make(map[string]bool)
+	for _, k := range keys {
+		wanted[k] = true
+	}
+	var found []Setting
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = strings.Trim(line, "[]")
+			section = strings.Replace(strings.Replace(section, ` "`, ".", 1), `"`, "", -1)
+			section = strings.ToLower(strings.TrimSpace(section))
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(kv[0]))
+		if section != "" {
+			key = section + "." + key
+		}
+		value := strings.Trim(strings.TrimSpace(kv[1]), `"'`)
+		if value != "" && (wanted[key] || subsectionProxy(key, wanted)) {
+			found = append(found, Setting{Source: path, Key: key, Value: value})
+		}
+	}
+	return found
+}
+
+// subsectionProxy matches git's per URL proxies, http.<url>.proxy
+func subsectionProxy(key string, wanted map[string]bool) bool {
+	return wanted["http.proxy"] && strings.HasPrefix(key, "http.") && strings.HasSuffix(key, ".proxy")
+}
+
+// pipConfigs lists the files pip reads its global settings from
+func pipConfigs(home string) []string {
+	paths := []string{
+		filepath.Join(home, ".pip", "pip.conf"),
+		filepath.Join(home, ".config", "pip", "pip.conf"),
+		"/etc/pip.conf",
+	}
+	switch runtime.GOOS {
+	case "windows":
+		paths = []string{filepath.Join(os.Getenv("APPDATA"), "pip", "pip.ini")}
+	case "darwin":
+		paths = append(paths, filepath.Join(home, "Library", "Application Support", "pip", "pip.conf"))
+	}
+	if env := os.Getenv("PIP_CONFIG_FILE"); env != "" {
+		paths = append(paths, env)
+	}
+	return paths
+}
+
+// dockerConfig is the part of ~/.docker/config.json that holds proxies
+type dockerConfig struct {
+	Proxies map[string]struct {
+		HTTPProxy  string `json:"httpProxy"`
+		HTTPSProxy string `json:"httpsProxy"`
+	} `json:"proxies"`
+}
+
+// fromDocker reads the proxies the Docker client passes to containers
+func fromDocker(path string) []Setting {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var cfg dockerConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		log.WithFields(log.Fields{"file": path, "error": err}).Debug("Could not read the Docker client config.")
+		return nil
+	}
+	var found []Setting
+	for name, p := range cfg.Proxies {
+		if p.HTTPProxy != "" {
+			found = append(found, Setting{Source: path, Key: "proxies." + name + ".httpProxy", Value: p.HTTPProxy})
+		}
+		if p.HTTPSProxy != "" {
+			found = append(found, Setting{Source: path, Key: "proxies." + name + ".httpsProxy", Value: p.HTTPSProxy})
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].Key < found[j].Key })
+	return found
+}
+
+// fromEtcEnvironment reads the proxy variables pam sets for every login
+func fromEtcEnvironment(path string) []Setting {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var found []Setting
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		kv := strings.SplitN(strings.TrimSpace(scanner.Text()), "=", 2)
+		if len(kv) != 2 || !strings.HasSuffix(strings.ToUpper(kv[0]), "_PROXY") || strings.EqualFold(kv[0], "NO_PROXY") {
+			continue
+		}
+		if value := strings.Trim(kv[1], `"'`); value != "" {
+			found = append(found, Setting{Source: path, Key: kv[0], Value: value})
+		}
+	}
+	return found
+}
+
+// Inconsistencies compares the discovered settings with each other and
+// with the proxy passed to nethelp.  Tools that disagree on the proxy
+// are a common reason for tests that fail from one language only.
+func Inconsistencies(found []Setting, userProxy *url.URL) []string {
+	if len(found) == 0 {
+		return nil
+	}
+	bySource := make(map[string][]string)
+	var addrs []string
+	for _, s := range found {
+		addr := s.Address()
+		if _, seen := bySource[addr]; !seen {
+			addrs = append(addrs, addr)
+		}
+		bySource[addr] = append(bySource[addr], s.Source+" "+s.Key)
+	}
+	var problems []string
+	if len(addrs) > 1 {
+		var uses []string
+		for _, addr := range addrs {
+			uses = append(uses, fmt.Sprintf("%s (%s)", addr, describe(bySource[addr])))
+		}
+		problems = append(problems, "The tools on this machine use different proxies: "+strings.Join(uses, "; "))
+	}
+	if userProxy == nil {
+		problems = append(problems, fmt.Sprintf("Tools on this machine use a proxy, %s, but nethelp runs without --proxy", strings.Join(addrs, ", ")))
+		return problems
+	}
+	want := address(userProxy.String())
+	for _, addr := range addrs {
+		if addr != want {
+			problems = append(problems, fmt.Sprintf("--proxy is %s, %s has %s", want, describe(bySource[addr]), addr))
+		}
+	}
+	return problems
+}
+
+// describe names the settings behind one proxy, or counts them when there are many
+func describe(settings []string) string {
+	if len(settings) > 2 {
+		return fmt.Sprintf("%d settings", len(settings))
+	}
+	return strings.Join(settings, ", ")
+}
+
+// CheckDiscovered warns about every inconsistency between the proxy
+// settings of the tools on this machine and userProxy
+func CheckDiscovered(userProxy *url.URL) {
+	for _, problem := range Inconsistencies(Discover(), userProxy) {
+		log.Warn(redact.String(problem))
+	}
+}
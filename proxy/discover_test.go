@@ -0,0 +1,233 @@
+package proxy
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// setenv sets an environment variable for the rest of the test
+func setenv(t *testing.T, key, value string) {
+	old, had := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if had {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+// writeFile writes content to name in a temporary directory and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFromJavaOpts(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+		opts string
+		want []Setting
+	}{
+		{
+			name: "https proxy with a port",
+			env:  "JAVA_OPTS",
+			opts: "-Xmx1g -Dhttps.proxyHost=proxy.inc.com -Dhttps.proxyPort=3128",
+			want: []Setting{{"JAVA_OPTS", "https.proxyHost", "http://proxy.inc.com:3128"}},
+		},
+		{
+			name: "default ports",
+			env:  "JAVA_TOOL_OPTIONS",
+			opts: "-Dhttp.proxyHost=proxy.inc.com -DsocksProxyHost=socks.inc.com",
+			want: []Setting{
+				{"JAVA_TOOL_OPTIONS", "http.proxyHost", "http://proxy.inc.com:80"},
+				{"JAVA_TOOL_OPTIONS", "socksProxyHost", "socks5://socks.inc.com:1080"},
+			},
+		},
+		{
+			name: "port without a host",
+			env:  "_JAVA_OPTIONS",
+			opts: "-Dhttps.proxyPort=3128 -Dfile.encoding=UTF-8",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, env := range []string{"JAVA_TOOL_OPTIONS", "JAVA_OPTS", "_JAVA_OPTIONS"} {
+				setenv(t, env, "")
+			}
+			setenv(t, tt.env, tt.opts)
+			if got := fromJavaOpts(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fromJavaOpts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromMaven(t *testing.T) {
+	path := writeFile(t, "settings.xml", `<settings>
+  <proxies>
+    <proxy>
+      <id>corp</id>
+      <active>true</active>
+      <protocol>http</protocol>
+      <host>proxy.inc.com</host>
+      <port>3128</port>
+    </proxy>
+    <proxy>
+      <id>old</id>
+      <active>false</active>
+      <host>old.inc.com</host>
+    </proxy>
+    <proxy>
+      <id>bare</id>
+      <host>bare.inc.com</host>
+    </proxy>
+  </proxies>
+</settings>`)
+	want := []Setting{
+		{path, "proxy corp", "http://proxy.inc.com:3128"},
+		{path, "proxy bare", "http://bare.inc.com:8080"},
+	}
+	if got := fromMaven(path); !reflect.DeepEqual(got, want) {
+		t.Errorf("fromMaven() = %v, want %v", got, want)
+	}
+	if got := fromMaven(writeFile(t, "settings.xml", "<settings><proxies>")); got != nil {
+		t.Errorf("fromMaven() of a broken file = %v", got)
+	}
+}
+
+func TestFromINI(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		keys    []string
+		want    []Setting
+	}{
+		{
+			name:    ".npmrc",
+			content: "registry=https://registry.npmjs.org/\nproxy=http://proxy.inc.com:3128\n; a comment\nhttps-proxy = \"http://proxy.inc.com:3128\"\n",
+			keys:    []string{"proxy", "https-proxy", "http-proxy"},
+			want: []Setting{
+				{Key: "proxy", Value: "http://proxy.inc.com:3128"},
+				{Key: "https-proxy", Value: "http://proxy.inc.com:3128"},
+			},
+		},
+		{
+			name:    ".gitconfig",
+			content: "[user]\n\tname = Bob\n[http]\n\tproxy = http://proxy.inc.com:3128\n[http \"https://github.com\"]\n\tproxy = socks5://socks.inc.com:1080\n[https]\n\tproxy =\n",
+			keys:    []string{"http.proxy", "https.proxy"},
+			want: []Setting{
+				{Key: "http.proxy", Value: "http://proxy.inc.com:3128"},
+				{Key: "http.https://github.com.proxy", Value: "socks5://socks.inc.com:1080"},
+			},
+		},
+		{
+			name:    "pip.conf",
+			content: "# pip settings\n[global]\ntimeout = 60\nproxy = proxy.inc.com:3128\n[install]\nproxy = other.inc.com:3128\n",
+			keys:    []string{"global.proxy"},
+			want:    []Setting{{Key: "global.proxy", Value: "proxy.inc.com:3128"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeFile(t, tt.name, tt.content)
+			for i := range tt.want {
+				tt.want[i].Source = path
+			}
+			if got := fromINI(path, tt.keys...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fromINI() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := fromINI(filepath.Join(t.TempDir(), "missing")); got != nil {
+		t.Errorf("fromINI() of a missing file = %v", got)
+	}
+}
+
+func TestFromDocker(t *testing.T) {
+	path := writeFile(t, "config.json", `{"auths": {}, "proxies": {"default": {"httpsProxy": "http://proxy.inc.com:3129", "httpProxy": "http://proxy.inc.com:3128", "noProxy": "localhost"}}}`)
+	want := []Setting{
+		{path, "proxies.default.httpProxy", "http://proxy.inc.com:3128"},
+		{path, "proxies.default.httpsProxy", "http://proxy.inc.com:3129"},
+	}
+	if got := fromDocker(path); !reflect.DeepEqual(got, want) {
+		t.Errorf("fromDocker() = %v, want %v", got, want)
+	}
+}
+
+func TestFromEtcEnvironment(t *testing.T) {
+	path := writeFile(t, "environment", "PATH=\"/usr/local/bin:/usr/bin\"\nhttp_proxy=\"http://proxy.inc.com:3128\"\nNO_PROXY=localhost\nHTTPS_PROXY='http://proxy.inc.com:3128'\nFTP_PROXY=\n")
+	want := []Setting{
+		{path, "http_proxy", "http://proxy.inc.com:3128"},
+		{path, "HTTPS_PROXY", "http://proxy.inc.com:3128"},
+	}
+	if got := fromEtcEnvironment(path); !reflect.DeepEqual(got, want) {
+		t.Errorf("fromEtcEnvironment() = %v, want %v", got, want)
+	}
+}
+
+func TestInconsistencies(t *testing.T) {
+	env := Setting{"environment", "HTTPS_PROXY", "http://proxy.inc.com:3128"}
+	tests := []struct {
+		name      string
+		found     []Setting
+		userProxy string
+		want      []string
+	}{
+		{name: "no settings", userProxy: "http://proxy.inc.com:3128"},
+		{
+			name:      "same proxy written differently",
+			found:     []Setting{env, {"~/.npmrc", "proxy", "PROXY.inc.com:3128"}},
+			userProxy: "http://proxy.inc.com:3128",
+		},
+		{
+			name:      "default port",
+			found:     []Setting{{"environment", "ALL_PROXY", "socks5://socks.inc.com"}},
+			userProxy: "socks5h://socks.inc.com:1080",
+		},
+		{
+			name:  "no --proxy",
+			found: []Setting{env},
+			want:  []string{"Tools on this machine use a proxy, proxy.inc.com:3128, but nethelp runs without --proxy"},
+		},
+		{
+			name:      "tools disagree",
+			found:     []Setting{env, {"JAVA_OPTS", "https.proxyHost", "http://other.inc.com:8080"}},
+			userProxy: "http://proxy.inc.com:3128",
+			want: []string{
+				"The tools on this machine use different proxies: proxy.inc.com:3128 (environment HTTPS_PROXY); other.inc.com:8080 (JAVA_OPTS https.proxyHost)",
+				"--proxy is proxy.inc.com:3128, JAVA_OPTS https.proxyHost has other.inc.com:8080",
+			},
+		},
+		{
+			name: "many settings are counted",
+			found: []Setting{
+				env,
+				{"environment", "https_proxy", "http://proxy.inc.com:3128"},
+				{"~/.gitconfig", "http.proxy", "http://proxy.inc.com:3128"},
+			},
+			userProxy: "socks5://socks.inc.com:1080",
+			want:      []string{"--proxy is socks.inc.com:1080, 3 settings has proxy.inc.com:3128"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var userProxy *url.URL
+			if tt.userProxy != "" {
+				userProxy, _ = url.Parse(tt.userProxy)
+			}
+			if got := Inconsistencies(tt.found, userProxy); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Inconsistencies() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
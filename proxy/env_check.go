@@ -1,68 +1,103 @@
 package proxy
 
 import (
+	"context"
 	"crypto/tls"
+	"fmt"
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/mdsauce/nethelp/redact"
 	log "github.com/sirupsen/logrus"
-	"github.com/spf13/cobra"
 )
 
-// AddProxy takes a user defined URL and routes tests through it
-func AddProxy(rawProxy string, cmd *cobra.Command) *url.URL {
-	var proxyURL *url.URL
-	var err error
-	var disableCheck bool
-	disableCheck, err = cmd.Flags().GetBool("lucky")
+// DefaultCheckURL is the site CheckProxy uses to prove the proxy works
+const DefaultCheckURL = "https://www.saucelabs.com"
+
+// Parse reads a user defined proxy URL.  An empty rawProxy means no proxy.
+func Parse(rawProxy string) (*url.URL, error) {
+	if rawProxy == "" {
+		return nil, nil
+	}
+	proxyURL, err := url.Parse(rawProxy)
 	if err != nil {
-		log.Fatal("Something went terribly wrong disabling the check with --lucky", err)
+		return nil, fmt.Errorf("proxy %s is not a valid URL.  %v", redact.String(rawProxy), err)
+	}
+	if password, ok := proxyURL.User.Password(); ok {
+		redact.AddSecret(password)
 	}
+	proxyURL.Scheme = strings.ToLower(proxyURL.Scheme)
+	if !supported(proxyURL.Scheme) || proxyURL.Hostname() == "" {
+		return nil, fmt.Errorf("proxy %s is not valid.  Enter it like protocol://host:port, with credentials if needed, and one of the protocols %s", redact.String(rawProxy), strings.Join(Schemes, ", "))
+	}
+	return proxyURL, nil
+}
 
-	if rawProxy != "" {
-		proxyURL, err = url.Parse(rawProxy)
-		if err != nil {
-			log.Fatalf("Panic while setting proxy %s.  Proxy not set and program exiting. %v", rawProxy, err)
-		}
-		// This takes care of HTTP calls globally
-		http.DefaultTransport = &http.Transport{Proxy: http.ProxyURL(proxyURL), TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+// NewTransport builds the HTTP transport for one run and routes it
+// through proxyURL when set.  dial opens every TCP connection.  HTTP and
+// HTTPS proxies get the requests, SOCKS proxies tunnel the connections.
+func NewTransport(proxyURL *url.URL, dial DialFunc) (*http.Transport, error) {
+	t := &http.Transport{
+		DialContext:         dial,
+		TLSHandshakeTimeout: 5 * time.Second,
+		// wait this long for 100 Continue before sending the body anyway
+		ExpectContinueTimeout: time.Second,
+		// a custom dialer turns HTTP/2 off unless asked for
+		ForceAttemptHTTP2: true,
 	}
-	// check that there are no env vars defining a proxy and everything works
-	if disableCheck != true {
-		CheckProxy(rawProxy)
+	if proxyURL == nil {
+		return t, nil
 	}
-	return proxyURL
+	t.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	switch proxyURL.Scheme {
+	case "http", "https":
+		t.Proxy = http.ProxyURL(proxyURL)
+		return t, nil
+	}
+	tunnel, err := Dialer(proxyURL, dial)
+	if err != nil {
+		return nil, err
+	}
+	t.DialContext = tunnel
+	return t, nil
 }
 
-// CheckProxy verifies the user defined or auto-detected proxy is viable for reaching public sites
-func CheckProxy(rawProxy string) {
-	resp, err := http.Get("https://www.saucelabs.com")
+// CheckProxy verifies the user defined or auto-detected proxy is viable for reaching public sites.
+// It only returns an error when a user defined proxy cannot be used.
+func CheckProxy(ctx context.Context, client *http.Client, checkURL string, proxyURL *url.URL) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", checkURL, nil)
 	if err != nil {
-		if rawProxy != "" {
-			log.WithFields(log.Fields{
-				"error": err,
-				"msg":   "www.saucelabs.com not reachable with this proxy",
-			}).Fatalf("Something is wrong with the user specified proxy %s.  It cannot be used.", rawProxy)
-		} else {
-			log.WithFields(log.Fields{
-				"error": err,
-				"msg":   "www.saucelabs.com not reachable.",
-			}).Warn("You may have no internet access or a proxy may be in use.")
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if proxyURL != nil {
+			return fmt.Errorf("something is wrong with the user specified proxy %s, %s is not reachable with it.  %v", redact.URL(proxyURL), checkURL, err)
 		}
+		log.WithFields(log.Fields{
+			"error": err,
+			"msg":   checkURL + " not reachable.",
+		}).Warn("You may have no internet access or a proxy may be in use.")
+		return nil
 	}
-	log.Info("Connection OK.  Able to reach www.saucelabs.com.", resp)
+	resp.Body.Close()
+	log.Info("Connection OK.  Able to reach ", checkURL, ". ", resp)
+	return nil
 }
 
-// CheckForEnvProxies double-checks that common environment variables aren't set
-func CheckForEnvProxies() {
-	proxyList := []string{"HTTP_PROXY", "HTTPS_PROXY", "PROXY", "ALL_PROXY"}
+// proxyList are the environment variables most tools read a proxy from
+var proxyList = []string{"HTTP_PROXY", "HTTPS_PROXY", "PROXY", "ALL_PROXY"}
+
+// EnvProxies returns the proxy environment variables that are set
+func EnvProxies() map[string]string {
+	found := make(map[string]string)
 	for _, proxy := range proxyList {
 		if os.Getenv(proxy) != "" {
-			log.WithFields(log.Fields{
-				"env var":         proxy,
-				"potential proxy": os.Getenv(proxy),
-			}).Warn("An environment variable for a Proxy may exist.  This will NOT be automatically used.  You must use the --proxy flag.")
+			found[proxy] = os.Getenv(proxy)
 		}
 	}
+	return found
 }
@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+)
+
+// HTTP versions a run can be forced to.  An empty version negotiates
+// HTTP/2 with ALPN and falls back to HTTP/1.1.
+const (
+	HTTP1 = "1.1"
+	HTTP2 = "2"
+)
+
+// WithHTTPVersion forces every HTTPS request of t to HTTP/1.1 or HTTP/2.
+// Forced to HTTP/2 a request fails when the server or the proxy in
+// between does not negotiate it.  Plain http:// always uses HTTP/1.1.
+func WithHTTPVersion(t *http.Transport, version string) (http.RoundTripper, error) {
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	switch version {
+	case "", "auto":
+		return t, nil
+	case HTTP1:
+		t.ForceAttemptHTTP2 = false
+		t.TLSClientConfig.NextProtos = []string{"http/1.1"}
+		// a non-nil empty map turns off the HTTP/2 support of the transport
+		t.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+		return t, nil
+	case HTTP2:
+		t.TLSClientConfig.NextProtos = []string{"h2"}
+		return requireHTTP2{t}, nil
+	}
+	return nil, fmt.Errorf("HTTP version %q is not valid.  Only 'auto', '%s' or '%s' are allowed", version, HTTP1, HTTP2)
+}
+
+// requireHTTP2 fails HTTPS responses that came back over HTTP/1.x
+type requireHTTP2 struct {
+	next http.RoundTripper
+}
+
+func (t requireHTTP2) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || req.URL.Scheme != "https" || resp.ProtoMajor == 2 {
+		return resp, err
+	}
+	resp.Body.Close()
+	alpn := "no ALPN"
+	if resp.TLS != nil && resp.TLS.NegotiatedProtocol != "" {
+		alpn = "ALPN " + resp.TLS.NegotiatedProtocol
+	}
+	return nil, fmt.Errorf("HTTP/2 was forced but %s was negotiated (%s)", resp.Proto, alpn)
+}
@@ -0,0 +1,162 @@
+// Package recommend ranks the Sauce Labs data centers by how fast this
+// machine connects to them, to pick --dc with numbers instead of guessing.
+package recommend
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/mdsauce/nethelp/connections"
+	"github.com/mdsauce/nethelp/endpoints"
+)
+
+// Target is one host of a data center that gets measured
+type Target struct {
+	// Role is ondemand or api
+	Role string
+	Addr string
+}
+
+// Stats sums up the samples of one measurement
+type Stats struct {
+	Min    time.Duration
+	Median time.Duration
+	Max    time.Duration
+}
+
+// Host is the measurement of one target
+type Host struct {
+	Target
+	Connect Stats
+	TLS     Stats
+	Samples int
+	Failed  int
+	// Error is the last error of a failed sample
+	Error string
+}
+
+// Score is one data center with the numbers it is ranked by
+type Score struct {
+	Region endpoints.Region
+	Hosts  []Host
+	// Total is the median connect plus TLS time, averaged over the hosts
+	Total   time.Duration
+	Samples int
+	Failed  int
+}
+
+// FailureLimit is the share of failed handshakes a data center may have
+// and still rank by Total.  A single lost handshake on a noisy network
+// should not push the fastest data center down the list.
+const FailureLimit = 0.1
+
+// FailureRate is the share of the handshakes with the region that failed
+func (s Score) FailureRate() float64 {
+	if s.Samples == 0 {
+		return 0
+	}
+	return float64(s.Failed) / float64(s.Samples)
+}
+
+// unreliable is the failure rate to rank by, zero up to FailureLimit
+func (s Score) unreliable() float64 {
+	if rate := s.FailureRate(); rate > FailureLimit {
+		return rate
+	}
+	return 0
+}
+
+// Targets returns the ondemand host and the API host of a region, or one
+// of them when both are the same.  Regions without a VDC use their
+// headless cloud.
+func Targets(r endpoints.Region) []Target {
+	for _, cloud := range []string{"vdc", "headless"} {
+		c, ok := r.Clouds[cloud]
+		if !ok || c.Host == "" {
+			continue
+		}
+		targets := []Target{{Role: "ondemand", Addr: net.JoinHostPort(c.Host, "443")}}
+		if u, err := url.Parse(c.REST); err == nil && u.Hostname() != "" && u.Hostname() != c.Host {
+			targets = append(targets, Target{Role: "api", Addr: net.JoinHostPort(u.Hostname(), "443")})
+		}
+		return targets
+	}
+	return nil
+}
+
+// Rank measures every host samples times with handshake and sorts the
+// regions, those that fail more than FailureLimit of the handshakes last
+// by their failure rate, the others by Total.  The regions are measured in
+// turns, so a slow moment of the network hits all of them alike, and a
+// host that serves several regions is measured once per turn.
+func Rank(ctx context.Context, regions []endpoints.Region, samples int, handshake func(addr string) connections.Timing) []Score {
+	type series struct {
+		connect, tls []time.Duration
+		failed       int
+		err          string
+	}
+	measured := make(map[string]*series)
+	for i := 0; i < samples && ctx.Err() == nil; i++ {
+		done := make(map[string]bool)
+		for _, r := range regions {
+			for _, t := range Targets(r) {
+				if done[t.Addr] {
+					continue
+				}
+				done[t.Addr] = true
+				s := measured[t.Addr]
+				if s == nil {
+					s = &series{}
+					measured[t.Addr] = s
+				}
+				timing := handshake(t.Addr)
+				if timing.Err != nil {
+					s.failed++
+					s.err = timing.Err.Error()
+					continue
+				}
+				s.connect = append(s.connect, timing.Connect)
+				s.tls = append(s.tls, timing.TLS)
+			}
+		}
+	}
+
+	var scores []Score
+	for _, r := range regions {
+		score := Score{Region: r}
+		for _, t := range Targets(r) {
+			s := measured[t.Addr]
+			if s == nil {
+				continue
+			}
+			h := Host{Target: t, Connect: stats(s.connect), TLS: stats(s.tls), Samples: len(s.connect) + s.failed, Failed: s.failed, Error: s.err}
+			score.Hosts = append(score.Hosts, h)
+			score.Samples += h.Samples
+			score.Failed += h.Failed
+			score.Total += h.Connect.Median + h.TLS.Median
+		}
+		if len(score.Hosts) > 0 {
+			score.Total /= time.Duration(len(score.Hosts))
+		}
+		scores = append(scores, score)
+	}
+	sort.SliceStable(scores, func(i, j int) bool {
+		if a, b := scores[i].unreliable(), scores[j].unreliable(); a != b {
+			return a < b
+		}
+		return scores[i].Total < scores[j].Total
+	})
+	return scores
+}
+
+func stats(samples []time.Duration) Stats {
+	if len(samples) == 0 {
+		return Stats{}
+	}
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return Stats{Min: sorted[0], Median: sorted[len(sorted)/2], Max: sorted[len(sorted)-1]}
+}
@@ -0,0 +1,143 @@
+package recommend
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mdsauce/nethelp/connections"
+	"github.com/mdsauce/nethelp/endpoints"
+)
+
+func region(key, host, rest string) endpoints.Region {
+	return endpoints.Region{Key: key, Clouds: map[string]endpoints.CloudHosts{"vdc": {Host: host, REST: rest}}}
+}
+
+// fakeNetwork answers handshakes with the latency of each host and fails
+// every failEvery-th handshake with it.  calls counts the handshakes.
+type fakeNetwork struct {
+	latency   map[string]time.Duration
+	failEvery map[string]int
+	calls     map[string]int
+}
+
+func (n *fakeNetwork) handshake(addr string) connections.Timing {
+	host, _, _ := net.SplitHostPort(addr)
+	n.calls[host]++
+	if every := n.failEvery[host]; every > 0 && n.calls[host]%every == 0 {
+		return connections.Timing{Err: errors.New("connection reset by peer")}
+	}
+	return connections.Timing{Connect: n.latency[host], TLS: n.latency[host]}
+}
+
+func TestRank(t *testing.T) {
+	const ms = time.Millisecond
+	tests := []struct {
+		name      string
+		latency   map[string]time.Duration
+		failEvery map[string]int
+		want      []string
+	}{
+		{
+			name:    "fastest first",
+			latency: map[string]time.Duration{"fast.example.com": 10 * ms, "slow.example.com": 50 * ms},
+			want:    []string{"fast", "slow"},
+		},
+		{
+			name:      "one lost handshake does not outrank a faster region",
+			latency:   map[string]time.Duration{"fast.example.com": 10 * ms, "slow.example.com": 50 * ms},
+			failEvery: map[string]int{"fast.example.com": 10},
+			want:      []string{"fast", "slow"},
+		},
+		{
+			name:      "unreliable regions rank last",
+			latency:   map[string]time.Duration{"fast.example.com": 10 * ms, "slow.example.com": 50 * ms},
+			failEvery: map[string]int{"fast.example.com": 2},
+			want:      []string{"slow", "fast"},
+		},
+		{
+			name:      "lower failure rate first among unreliable regions",
+			latency:   map[string]time.Duration{"fast.example.com": 10 * ms, "slow.example.com": 50 * ms},
+			failEvery: map[string]int{"fast.example.com": 2, "slow.example.com": 5},
+			want:      []string{"slow", "fast"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			regions := []endpoints.Region{
+				region("slow", "slow.example.com", ""),
+				region("fast", "fast.example.com", ""),
+			}
+			n := &fakeNetwork{latency: tt.latency, failEvery: tt.failEvery, calls: make(map[string]int)}
+			scores := Rank(context.Background(), regions, 10, n.handshake)
+			var got []string
+			for _, s := range scores {
+				got = append(got, s.Region.Key)
+				if s.Samples != 10 {
+					t.Errorf("%s has %d samples, want 10", s.Region.Key, s.Samples)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Rank() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankMeasuresSharedHostsOnce(t *testing.T) {
+	regions := []endpoints.Region{
+		region("east", "ondemand.east.example.com", "https://api.example.com"),
+		region("west", "ondemand.west.example.com", "https://api.example.com"),
+	}
+	n := &fakeNetwork{latency: map[string]time.Duration{}, calls: make(map[string]int)}
+	scores := Rank(context.Background(), regions, 5, n.handshake)
+	want := map[string]int{"ondemand.east.example.com": 5, "ondemand.west.example.com": 5, "api.example.com": 5}
+	if !reflect.DeepEqual(n.calls, want) {
+		t.Errorf("handshakes per host = %v, want %v", n.calls, want)
+	}
+	for _, s := range scores {
+		if s.Samples != 10 {
+			t.Errorf("%s has %d samples, want 10", s.Region.Key, s.Samples)
+		}
+	}
+}
+
+func TestTargets(t *testing.T) {
+	tests := []struct {
+		name   string
+		region endpoints.Region
+		want   []Target
+	}{
+		{
+			name:   "ondemand and API host",
+			region: region("na", "ondemand.saucelabs.com", "https://saucelabs.com"),
+			want:   []Target{{"ondemand", "ondemand.saucelabs.com:443"}, {"api", "saucelabs.com:443"}},
+		},
+		{
+			name:   "API on the ondemand host",
+			region: region("na", "ondemand.saucelabs.com", "https://ondemand.saucelabs.com/rest"),
+			want:   []Target{{"ondemand", "ondemand.saucelabs.com:443"}},
+		},
+		{
+			name: "headless only",
+			region: endpoints.Region{Key: "east", Clouds: map[string]endpoints.CloudHosts{
+				"headless": {Host: "ondemand.us-east-1.saucelabs.com", REST: "https://us-east-1.saucelabs.com"},
+			}},
+			want: []Target{{"ondemand", "ondemand.us-east-1.saucelabs.com:443"}, {"api", "us-east-1.saucelabs.com:443"}},
+		},
+		{
+			name:   "no VDC or headless cloud",
+			region: endpoints.Region{Key: "rdc", Clouds: map[string]endpoints.CloudHosts{"rdc": {Host: "us1.appium.testobject.com"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Targets(tt.region); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Targets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package redact
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// Formatter masks secrets in the message and fields of every log entry
+// before handing it to the wrapped formatter
+type Formatter struct {
+	log.Formatter
+}
+
+// Format implements logrus.Formatter
+func (f *Formatter) Format(entry *log.Entry) ([]byte, error) {
+	clean := entry.Dup()
+	clean.Level = entry.Level
+	clean.Message = String(entry.Message)
+	for k, v := range entry.Data {
+		clean.Data[k] = Value(v)
+	}
+	return f.Formatter.Format(clean)
+}
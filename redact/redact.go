@@ -0,0 +1,157 @@
+package redact
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask replaces every secret nethelp hides
+const Mask = "****"
+
+var (
+	secrets []string
+	mu      sync.RWMutex
+
+	// user:password@host in URLs, with or without a scheme
+	userinfoRE = regexp.MustCompile(`([A-Za-z0-9._~%!$&'()*+,;=-]+):([^\s@/:]+)@([A-Za-z0-9.\[\]-])`)
+	// Authorization and Proxy-Authorization as header lines, header maps or log fields
+	authHeaderRE = regexp.MustCompile(`(?i)((?:proxy-)?authorization["']?\s*[:=]\s*\[?["']?)(?:(basic|bearer|digest|negotiate|ntlm)\s+)?[^\s"'\],]+`)
+	// key=value or key: value pairs that name a secret
+	secretPairRE = regexp.MustCompile(`(?i)((?:access[_-]?key|password|passwd|secret)["']?\s*[:=]\s*["']?)[^\s"',&]+`)
+	// usernames in Sauce Labs REST paths
+	restUserRE    = regexp.MustCompile(`/rest/v1/users/[^/?#\s"]+`)
+	restTunnelsRE = regexp.MustCompile(`/rest/v1/[^/?#\s"]+/tunnels`)
+)
+
+// AddSecret registers a value that must never show up in logs or output
+func AddSecret(secret string) {
+	if len(secret) < 4 {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, s := range secrets {
+		if s == secret {
+			return
+		}
+	}
+	secrets = append(secrets, secret)
+	// longest first so a secret containing another one is masked whole
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+}
+
+// AddCredentials registers an access key and the basic auth token built from it
+func AddCredentials(username, accessKey string) {
+	AddSecret(accessKey)
+	AddSecret(base64.StdEncoding.EncodeToString([]byte(username + ":" + accessKey)))
+}
+
+// String masks proxy passwords, access keys, Authorization headers and
+// usernames in REST URLs found anywhere in s
+func String(s string) string {
+	mu.RLock()
+	for _, secret := range secrets {
+		s = strings.Replace(s, secret, Mask, -1)
+	}
+	mu.RUnlock()
+	s = userinfoRE.ReplaceAllString(s, "$1:"+Mask+"@$3")
+	s = authHeaderRE.ReplaceAllStringFunc(s, func(m string) string {
+		sub := authHeaderRE.FindStringSubmatch(m)
+		if sub[2] != "" {
+			return sub[1] + sub[2] + " " + Mask
+		}
+		return sub[1] + Mask
+	})
+	s = secretPairRE.ReplaceAllString(s, "${1}"+Mask)
+	s = restUserRE.ReplaceAllString(s, "/rest/v1/users/"+Mask)
+	s = restTunnelsRE.ReplaceAllString(s, "/rest/v1/"+Mask+"/tunnels")
+	return s
+}
+
+// URL prints u without its password
+func URL(u *url.URL) string {
+	if u == nil {
+		return "<nil>"
+	}
+	return String(u.Redacted())
+}
+
+// Header copies h with credentials and cookies masked
+func Header(h http.Header) http.Header {
+	clean := make(http.Header, len(h))
+	for k, v := range h {
+		switch http.CanonicalHeaderKey(k) {
+		case "Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie":
+			clean[k] = []string{Mask}
+		default:
+			vals := make([]string, len(v))
+			for i := range v {
+				vals[i] = String(v[i])
+			}
+			clean[k] = vals
+		}
+	}
+	return clean
+}
+
+// Request summarizes req without credentials
+func Request(req *http.Request) string {
+	if req == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("%s %s %v", req.Method, URL(req.URL), Header(req.Header))
+}
+
+// Response summarizes resp and the request behind it without credentials
+func Response(resp *http.Response) string {
+	if resp == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("%s %s %v request: %s", resp.Proto, resp.Status, Header(resp.Header), Request(resp.Request))
+}
+
+// Value masks secrets in anything that ends up in a log field
+func Value(v interface{}) interface{} {
+	switch t := v.(type) {
+	case nil, bool, int, int64, float64:
+		return v
+	case string:
+		return String(t)
+	case error:
+		return String(t.Error())
+	case *url.URL:
+		return URL(t)
+	case http.Header:
+		return Header(t)
+	case *http.Request:
+		return Request(t)
+	case *http.Response:
+		return Response(t)
+	case fmt.Stringer:
+		return String(t.String())
+	default:
+		return String(fmt.Sprintf("%+v", t))
+	}
+}
+
+// Printf is fmt.Printf with every secret masked
+func Printf(format string, a ...interface{}) {
+	fmt.Print(String(fmt.Sprintf(format, a...)))
+}
+
+// Fprintf is fmt.Fprintf with every secret masked
+func Fprintf(w io.Writer, format string, a ...interface{}) {
+	fmt.Fprint(w, String(fmt.Sprintf(format, a...)))
+}
+
+// Println is fmt.Println with every secret masked
+func Println(a ...interface{}) {
+	fmt.Print(String(fmt.Sprintln(a...)))
+}
@@ -0,0 +1,173 @@
+package remap
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// Rule sends connections meant for Host:Port somewhere else, like
+// curl's --resolve and --connect-to.  An empty Host or Port matches
+// anything and an empty ToHost or ToPort keeps the original value.
+type Rule struct {
+	Flag   string
+	Host   string
+	Port   string
+	ToHost string
+	ToPort string
+}
+
+// Rules is an ordered list of remapping rules.  --connect-to rules are
+// applied before --resolve rules, the same order curl uses.
+type Rules []Rule
+
+// ParseResolve reads a curl style --resolve value, host:port:addr
+func ParseResolve(raw string) (Rule, error) {
+	parts := strings.SplitN(raw, ":", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return Rule{}, fmt.Errorf("--resolve %s must look like host:port:addr", raw)
+	}
+	addr := strings.Trim(parts[2], "[]")
+	if net.ParseIP(addr) == nil {
+		return Rule{}, fmt.Errorf("--resolve %s: %s is not an IP address", raw, parts[2])
+	}
+	return Rule{Flag: "resolve", Host: parts[0], Port: parts[1], ToHost: addr, ToPort: parts[1]}, nil
+}
+
+// ParseConnectTo reads a curl style --connect-to value, host1:port1:host2:port2
+func ParseConnectTo(raw string) (Rule, error) {
+	parts := splitHostPorts(raw)
+	if len(parts) != 4 {
+		return Rule{}, fmt.Errorf("--connect-to %s must look like host1:port1:host2:port2", raw)
+	}
+	if parts[2] == "" && parts[3] == "" {
+		return Rule{}, fmt.Errorf("--connect-to %s does not change the host or the port", raw)
+	}
+	return Rule{Flag: "connect-to", Host: parts[0], Port: parts[1], ToHost: parts[2], ToPort: parts[3]}, nil
+}
+
+// splitHostPorts splits on colons that are not inside [IPv6] brackets
+func splitHostPorts(raw string) []string {
+	var parts []string
+	var cur strings.Builder
+	inBrackets := false
+	for _, r := range raw {
+		switch {
+		case r == '[':
+			inBrackets = true
+		case r == ']':
+			inBrackets = false
+		case r == ':' && !inBrackets:
+			parts = append(parts, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	return append(parts, cur.String())
+}
+
+func (r Rule) matches(host, port string) bool {
+	return (r.Host == "" || strings.EqualFold(r.Host, host)) && (r.Port == "" || r.Port == port)
+}
+
+func (r Rule) apply(host, port string) (string, string) {
+	if r.ToHost != "" {
+		host = r.ToHost
+	}
+	if r.ToPort != "" {
+		port = r.ToPort
+	}
+	return host, port
+}
+
+// Lookup returns the address that should be dialed instead of addr
+// and whether any rule matched
+func (rs Rules) Lookup(addr string) (string, bool) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr, false
+	}
+	matched := false
+	for _, flag := range []string{"connect-to", "resolve"} {
+		for _, r := range rs {
+			if r.Flag == flag && r.matches(host, port) {
+				host, port = r.apply(host, port)
+				matched = true
+				break
+			}
+		}
+	}
+	return net.JoinHostPort(host, port), matched
+}
+
+// Remapper applies a set of rules to every dial of one run and remembers
+// which addresses it changed, so output can mark remapped endpoints
+type Remapper struct {
+	Rules Rules
+
+	applied map[string]string
+	mu      sync.Mutex
+}
+
+// New builds a Remapper for rules.  A Remapper without rules dials every address as is.
+func New(rules Rules) *Remapper {
+	return &Remapper{Rules: rules, applied: make(map[string]string)}
+}
+
+// Address remaps addr with the rules and remembers the result
+func (m *Remapper) Address(addr string) string {
+	if m == nil {
+		return addr
+	}
+	to, ok := m.Rules.Lookup(addr)
+	if ok {
+		m.mu.Lock()
+		m.applied[addr] = to
+		m.mu.Unlock()
+	}
+	return to
+}
+
+// Applied returns the address host:port was remapped to, if it was
+func (m *Remapper) Applied(hostport string) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	to, ok := m.applied[hostport]
+	return to, ok
+}
+
+// DialContext wraps dialer so every connection honours the rules.
+// TLS server names and Host headers come from the request URL and are not changed.
+func (m *Remapper) DialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, m.Address(addr))
+	}
+}
+
+// Dialer implements golang.org/x/net/proxy.Dialer so proxy dialers
+// can forward through the remapping rules as well
+func (m *Remapper) Dialer(forward *net.Dialer) Dialer {
+	return Dialer{Forward: forward, remapper: m}
+}
+
+// Dialer dials the remapped address of every addr
+type Dialer struct {
+	Forward  *net.Dialer
+	remapper *Remapper
+}
+
+// Dial connects to the remapped address of addr
+func (d Dialer) Dial(network, addr string) (net.Conn, error) {
+	return d.Forward.Dial(network, d.remapper.Address(addr))
+}
+
+// DialContext connects to the remapped address of addr
+func (d Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return d.Forward.DialContext(ctx, network, d.remapper.Address(addr))
+}
@@ -0,0 +1,81 @@
+package remap
+
+import "testing"
+
+func TestParseResolve(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    Rule
+		wantErr bool
+	}{
+		{raw: "ondemand.saucelabs.com:443:1.2.3.4", want: Rule{Flag: "resolve", Host: "ondemand.saucelabs.com", Port: "443", ToHost: "1.2.3.4", ToPort: "443"}},
+		{raw: "ondemand.saucelabs.com:443:[2001:db8::1]", want: Rule{Flag: "resolve", Host: "ondemand.saucelabs.com", Port: "443", ToHost: "2001:db8::1", ToPort: "443"}},
+		{raw: "ondemand.saucelabs.com:443:2001:db8::1", want: Rule{Flag: "resolve", Host: "ondemand.saucelabs.com", Port: "443", ToHost: "2001:db8::1", ToPort: "443"}},
+		{raw: "ondemand.saucelabs.com:443", wantErr: true},
+		{raw: "ondemand.saucelabs.com::1.2.3.4", wantErr: true},
+		{raw: "ondemand.saucelabs.com:443:mirror.internal", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseResolve(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseResolve(%q) error = %v, want error %v", tt.raw, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseResolve(%q) = %+v, want %+v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConnectTo(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    Rule
+		wantErr bool
+	}{
+		{raw: "ondemand.saucelabs.com:443:mirror.internal:8443", want: Rule{Flag: "connect-to", Host: "ondemand.saucelabs.com", Port: "443", ToHost: "mirror.internal", ToPort: "8443"}},
+		{raw: "::mirror.internal:", want: Rule{Flag: "connect-to", ToHost: "mirror.internal"}},
+		{raw: "ondemand.saucelabs.com:443:[::1]:8443", want: Rule{Flag: "connect-to", Host: "ondemand.saucelabs.com", Port: "443", ToHost: "::1", ToPort: "8443"}},
+		{raw: "ondemand.saucelabs.com:443:mirror.internal", wantErr: true},
+		{raw: "ondemand.saucelabs.com:443::", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseConnectTo(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseConnectTo(%q) error = %v, want error %v", tt.raw, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseConnectTo(%q) = %+v, want %+v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	resolve, _ := ParseResolve("mirror.internal:8443:10.0.0.5")
+	connectTo, _ := ParseConnectTo("ondemand.saucelabs.com:443:mirror.internal:8443")
+	other, _ := ParseResolve("api.saucelabs.com:443:10.0.0.6")
+	rules := Rules{resolve, other, connectTo}
+	tests := []struct {
+		addr      string
+		want      string
+		wantMatch bool
+	}{
+		// --connect-to first, then --resolve on the result, like curl
+		{"ondemand.saucelabs.com:443", "10.0.0.5:8443", true},
+		{"OnDemand.SauceLabs.com:443", "10.0.0.5:8443", true},
+		{"api.saucelabs.com:443", "10.0.0.6:443", true},
+		{"api.saucelabs.com:80", "api.saucelabs.com:80", false},
+		{"not an address", "not an address", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			got, matched := rules.Lookup(tt.addr)
+			if got != tt.want || matched != tt.wantMatch {
+				t.Errorf("Lookup(%q) = %q, %v, want %q, %v", tt.addr, got, matched, tt.want, tt.wantMatch)
+			}
+		})
+	}
+}
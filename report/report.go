@@ -0,0 +1,208 @@
+package report
+
+import (
+	_ "embed" // needed for the HTML template
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mdsauce/nethelp/connections"
+	"github.com/mdsauce/nethelp/diagnose"
+	"github.com/mdsauce/nethelp/redact"
+)
+
+// htmlTemplate is a single page with inline CSS so the file can be forwarded on its own
+//
+//go:embed report.html
+var htmlTemplate string
+
+// Report is everything one HTML report shows
+type Report struct {
+	Generated  time.Time
+	Version    string
+	Proxy      string
+	EnvProxies map[string]string
+	Results    []connections.Result
+}
+
+type row struct {
+	connections.Result
+	Class  string
+	Took   string
+	BarPct int
+}
+
+type datacenter struct {
+	Name string
+	Rows []row
+}
+
+type group struct {
+	Name        string
+	Datacenters []datacenter
+	Passed      int
+	Total       int
+}
+
+type tlsFinding struct {
+	Issuer    string
+	Versions  string
+	Endpoints []string
+}
+
+type page struct {
+	Report
+	Groups       []group
+	Passed       int
+	Failed       int
+	Unauthorized int
+	TLS          []tlsFinding
+	Remediation  []string
+}
+
+var groupNames = map[string]string{
+	"vdc":      "Virtual Device Cloud",
+	"rdc":      "Real Device Cloud",
+	"headless": "Headless",
+	"public":   "Public internet",
+	"tcp":      "TCP",
+}
+
+// WriteHTML renders r as a self-contained HTML page
+func WriteHTML(w io.Writer, r Report) error {
+	tmpl, err := template.New("report").Funcs(template.FuncMap{
+		"redact": redact.String,
+		"group": func(name string) string {
+			if full, ok := groupNames[name]; ok {
+				return full
+			}
+			return name
+		},
+	}).Parse(htmlTemplate)
+	if err != nil {
+		return err
+	}
+	return tmpl.Execute(w, build(r))
+}
+
+func build(r Report) page {
+	p := page{Report: r}
+	var longest time.Duration
+	for _, res := range r.Results {
+		if res.Duration > longest {
+			longest = res.Duration
+		}
+	}
+
+	var order []string
+	byGroup := make(map[string]map[string][]row)
+	for _, res := range r.Results {
+		if _, ok := byGroup[res.Group]; !ok {
+			byGroup[res.Group] = make(map[string][]row)
+			order = append(order, res.Group)
+		}
+		dc := res.Datacenter
+		if dc == "" {
+			dc = "other"
+		}
+		rw := row{Result: res, Class: string(res.Outcome), Took: res.Duration.Round(time.Millisecond).String()}
+		if longest > 0 {
+			rw.BarPct = int(100 * res.Duration / longest)
+		}
+		byGroup[res.Group][dc] = append(byGroup[res.Group][dc], rw)
+
+		switch res.Outcome {
+		case connections.Reachable:
+			p.Passed++
+		case connections.Unauthorized:
+			p.Unauthorized++
+		default:
+			p.Failed++
+		}
+	}
+	for _, name := range order {
+		g := group{Name: name}
+		var dcs []string
+		for dc := range byGroup[name] {
+			dcs = append(dcs, dc)
+		}
+		sort.Strings(dcs)
+		for _, dc := range dcs {
+			rows := byGroup[name][dc]
+			g.Datacenters = append(g.Datacenters, datacenter{Name: dc, Rows: rows})
+			for _, rw := range rows {
+				g.Total++
+				if rw.Outcome == connections.Reachable {
+					g.Passed++
+				}
+			}
+		}
+		p.Groups = append(p.Groups, g)
+	}
+	p.TLS = tlsFindings(r.Results)
+	p.Remediation = remediation(r)
+	return p
+}
+
+// tlsFindings lists every certificate issuer seen, so TLS inspection
+// by a corporate proxy stands out next to the public CAs Sauce Labs uses
+func tlsFindings(results []connections.Result) []tlsFinding {
+	var order []string
+	byIssuer := make(map[string]*tlsFinding)
+	versions := make(map[string]map[string]bool)
+	for _, res := range results {
+		if res.TLSIssuer == "" {
+			continue
+		}
+		f, ok := byIssuer[res.TLSIssuer]
+		if !ok {
+			f = &tlsFinding{Issuer: res.TLSIssuer}
+			byIssuer[res.TLSIssuer] = f
+			versions[res.TLSIssuer] = make(map[string]bool)
+			order = append(order, res.TLSIssuer)
+		}
+		f.Endpoints = append(f.Endpoints, res.Endpoint)
+		versions[res.TLSIssuer][res.TLSVersion] = true
+	}
+	var findings []tlsFinding
+	for _, issuer := range order {
+		f := byIssuer[issuer]
+		var vs []string
+		for v := range versions[issuer] {
+			vs = append(vs, v)
+		}
+		sort.Strings(vs)
+		f.Versions = strings.Join(vs, ", ")
+		findings = append(findings, *f)
+	}
+	return findings
+}
+
+// remediation turns the failures of a run into next steps
+func remediation(r Report) []string {
+	var steps []string
+	seen := make(map[string]bool)
+	add := func(step string) {
+		if !seen[step] {
+			seen[step] = true
+			steps = append(steps, step)
+		}
+	}
+	for _, d := range diagnose.Diagnose(r.Results, r.Proxy != "") {
+		add(d.Summary + "  " + strings.Join(d.Steps, "  "))
+	}
+	for _, res := range r.Results {
+		switch {
+		case res.Outcome == connections.Unauthorized:
+			add("The REST API rejected the credentials.  Check the username and access key for each data center under User Settings in the Sauce Labs app.")
+		case res.Outcome == connections.Failed && res.Group == "public":
+			add("Public sites are not reachable either.  Check the general internet access of this machine before looking at Sauce Labs specific rules.")
+		}
+	}
+	if len(r.EnvProxies) > 0 && r.Proxy == "" {
+		add("Proxy environment variables are set but nethelp ran without --proxy.  Run again with --proxy set to the same value your tests use.")
+	}
+	return steps
+}
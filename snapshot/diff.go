@@ -0,0 +1,150 @@
+package snapshot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mdsauce/nethelp/connections"
+)
+
+// Kinds of change Diff reports
+const (
+	Flipped = "state"
+	Slower  = "latency"
+	Issuer  = "tls"
+	DNS     = "dns"
+	Proxy   = "proxy"
+	Version = "http"
+)
+
+var kindOrder = map[string]int{Flipped: 0, Slower: 1, Version: 2, Issuer: 3, DNS: 4, Proxy: 5}
+
+// Change is one difference between a baseline and a later run
+type Change struct {
+	Kind    string
+	Subject string
+	Before  string
+	After   string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Subject, c.Before, c.After)
+}
+
+// Diff compares a later run with a baseline.  It reports checks whose
+// outcome flipped, checks that got slower by more than threshold, checks
+// that negotiated another HTTP version, TLS issuers the baseline never
+// saw, changed DNS answers and changed proxy settings.
+func Diff(baseline, current Snapshot, threshold time.Duration) []Change {
+	var changes []Change
+	before := byKey(baseline.Results)
+	after := byKey(current.Results)
+
+	for _, key := range sortedKeys(before, after) {
+		b, inBefore := before[key]
+		a, inAfter := after[key]
+		switch {
+		case !inAfter:
+			changes = append(changes, Change{Flipped, key, string(b.Outcome), "not run"})
+		case !inBefore:
+			changes = append(changes, Change{Flipped, key, "not run", string(a.Outcome)})
+		case a.Outcome != b.Outcome:
+			changes = append(changes, Change{Flipped, key, string(b.Outcome), outcomeWithError(a)})
+		case a.Outcome == connections.Reachable && a.Duration-b.Duration > threshold:
+			changes = append(changes, Change{Slower, key, b.Duration.Round(time.Millisecond).String(), a.Duration.Round(time.Millisecond).String()})
+		}
+		// a check can get slower because it lost HTTP/2, both are reported
+		if inBefore && inAfter && a.Proto != "" && b.Proto != "" && a.Proto != b.Proto {
+			changes = append(changes, Change{Version, key, b.Proto, a.Proto})
+		}
+	}
+
+	known := make(map[string]bool)
+	for _, r := range baseline.Results {
+		known[r.TLSIssuer] = true
+	}
+	reported := make(map[string]bool)
+	for _, r := range current.Results {
+		if r.TLSIssuer == "" || known[r.TLSIssuer] || reported[r.TLSIssuer] {
+			continue
+		}
+		reported[r.TLSIssuer] = true
+		changes = append(changes, Change{Issuer, Host(r), "not seen", r.TLSIssuer})
+	}
+
+	for host, b := range baseline.DNS {
+		a, ok := current.DNS[host]
+		if ok && strings.Join(a, ", ") != strings.Join(b, ", ") {
+			changes = append(changes, Change{DNS, host, strings.Join(b, ", "), strings.Join(a, ", ")})
+		}
+	}
+
+	if baseline.Proxy != current.Proxy {
+		changes = append(changes, Change{Proxy, "--proxy", orNone(baseline.Proxy), orNone(current.Proxy)})
+	}
+	envs := make(map[string]bool)
+	for env := range baseline.EnvProxies {
+		envs[env] = true
+	}
+	for env := range current.EnvProxies {
+		envs[env] = true
+	}
+	for env := range envs {
+		if baseline.EnvProxies[env] != current.EnvProxies[env] {
+			changes = append(changes, Change{Proxy, env, orNone(baseline.EnvProxies[env]), orNone(current.EnvProxies[env])})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return kindOrder[changes[i].Kind] < kindOrder[changes[j].Kind] ||
+			kindOrder[changes[i].Kind] == kindOrder[changes[j].Kind] && changes[i].Subject < changes[j].Subject
+	})
+	return changes
+}
+
+// Key identifies a check across runs.  Session test steps and method
+// matrix requests share an endpoint, so the step or request is part of their key.
+func Key(r connections.Result) string {
+	key := r.Group + " " + r.Check + " " + r.Endpoint
+	if r.Check == "session" || r.Check == "method" {
+		key += " " + r.Detail
+	}
+	return key
+}
+
+func byKey(results []connections.Result) map[string]connections.Result {
+	m := make(map[string]connections.Result, len(results))
+	for _, r := range results {
+		m[Key(r)] = r
+	}
+	return m
+}
+
+func sortedKeys(a, b map[string]connections.Result) []string {
+	var keys []string
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func outcomeWithError(r connections.Result) string {
+	if r.Error != "" {
+		return fmt.Sprintf("%s (%s)", r.Outcome, r.Error)
+	}
+	return string(r.Outcome)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
@@ -0,0 +1,96 @@
+package snapshot
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mdsauce/nethelp/connections"
+)
+
+func result(endpoint string, outcome connections.Outcome, d time.Duration, proto string) connections.Result {
+	return connections.Result{Group: "vdc", Check: "http", Endpoint: endpoint, Outcome: outcome, Duration: d, Proto: proto}
+}
+
+func TestDiff(t *testing.T) {
+	const api = "https://api.us-west-1.saucelabs.com"
+	const hub = "https://ondemand.us-west-1.saucelabs.com"
+	ms := time.Millisecond
+	tests := []struct {
+		name     string
+		baseline Snapshot
+		current  Snapshot
+		want     []Change
+	}{
+		{
+			name:     "nothing changed",
+			baseline: Snapshot{Results: []connections.Result{result(api, connections.Reachable, 100*ms, "HTTP/2.0")}},
+			current:  Snapshot{Results: []connections.Result{result(api, connections.Reachable, 120*ms, "HTTP/2.0")}},
+		},
+		{
+			name:     "flipped to failed",
+			baseline: Snapshot{Results: []connections.Result{result(api, connections.Reachable, 100*ms, "")}},
+			current:  Snapshot{Results: []connections.Result{{Group: "vdc", Check: "http", Endpoint: api, Outcome: connections.Failed, Error: "i/o timeout"}}},
+			want:     []Change{{Flipped, "vdc http " + api, "reachable", "failed (i/o timeout)"}},
+		},
+		{
+			name:     "checks that ran only once",
+			baseline: Snapshot{Results: []connections.Result{result(api, connections.Reachable, 0, "")}},
+			current:  Snapshot{Results: []connections.Result{result(hub, connections.Failed, 0, "")}},
+			want: []Change{
+				{Flipped, "vdc http " + api, "reachable", "not run"},
+				{Flipped, "vdc http " + hub, "not run", "failed"},
+			},
+		},
+		{
+			name:     "slower and downgraded",
+			baseline: Snapshot{Results: []connections.Result{result(api, connections.Reachable, 100*ms, "HTTP/2.0")}},
+			current:  Snapshot{Results: []connections.Result{result(api, connections.Reachable, 600*ms, "HTTP/1.1")}},
+			want: []Change{
+				{Slower, "vdc http " + api, "100ms", "600ms"},
+				{Version, "vdc http " + api, "HTTP/2.0", "HTTP/1.1"},
+			},
+		},
+		{
+			name:     "downgraded only",
+			baseline: Snapshot{Results: []connections.Result{result(api, connections.Reachable, 100*ms, "HTTP/2.0")}},
+			current:  Snapshot{Results: []connections.Result{result(api, connections.Reachable, 100*ms, "HTTP/1.1")}},
+			want:     []Change{{Version, "vdc http " + api, "HTTP/2.0", "HTTP/1.1"}},
+		},
+		{
+			name:     "new TLS issuer",
+			baseline: Snapshot{Results: []connections.Result{{Group: "vdc", Check: "http", Endpoint: api, Outcome: connections.Reachable, TLSIssuer: "CN=DigiCert"}}},
+			current:  Snapshot{Results: []connections.Result{{Group: "vdc", Check: "http", Endpoint: api, Outcome: connections.Reachable, TLSIssuer: "CN=Zscaler Root CA"}}},
+			want:     []Change{{Issuer, "api.us-west-1.saucelabs.com", "not seen", "CN=Zscaler Root CA"}},
+		},
+		{
+			name:     "DNS and proxy",
+			baseline: Snapshot{DNS: map[string][]string{"api.us-west-1.saucelabs.com": {"1.2.3.4"}}, EnvProxies: map[string]string{"HTTPS_PROXY": "http://proxy.inc.com:8080"}},
+			current:  Snapshot{DNS: map[string][]string{"api.us-west-1.saucelabs.com": {"5.6.7.8"}}, Proxy: "socks5://proxy.inc.com:1080"},
+			want: []Change{
+				{DNS, "api.us-west-1.saucelabs.com", "1.2.3.4", "5.6.7.8"},
+				{Proxy, "--proxy", "none", "socks5://proxy.inc.com:1080"},
+				{Proxy, "HTTPS_PROXY", "http://proxy.inc.com:8080", "none"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Diff(tt.baseline, tt.current, 250*ms)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	step := connections.Result{Group: "vdc", Check: "session", Endpoint: "https://ondemand.saucelabs.com/wd/hub/session", Detail: "new session"}
+	if got, want := Key(step), "vdc session https://ondemand.saucelabs.com/wd/hub/session new session"; got != want {
+		t.Errorf("Key() = %q, want %q", got, want)
+	}
+	plain := connections.Result{Group: "vdc", Check: "http", Endpoint: "https://ondemand.saucelabs.com", Detail: "build 1"}
+	if got, want := Key(plain), "vdc http https://ondemand.saucelabs.com"; got != want {
+		t.Errorf("Key() = %q, want %q", got, want)
+	}
+}
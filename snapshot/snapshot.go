@@ -0,0 +1,115 @@
+// Package snapshot stores the structured results of a run together with the
+// network settings it ran under, so two runs can be compared later.
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/mdsauce/nethelp/connections"
+	"github.com/mdsauce/nethelp/proxy"
+	"github.com/mdsauce/nethelp/redact"
+	"github.com/mdsauce/nethelp/suite"
+)
+
+// Snapshot is one run as saved to disk.  Secrets are masked before saving.
+type Snapshot struct {
+	Taken      time.Time            `json:"taken"`
+	Version    string               `json:"version"`
+	Hostname   string               `json:"hostname,omitempty"`
+	Proxy      string               `json:"proxy,omitempty"`
+	EnvProxies map[string]string    `json:"env_proxies,omitempty"`
+	DNS        map[string][]string  `json:"dns,omitempty"`
+	Results    []connections.Result `json:"results"`
+}
+
+// Take builds a snapshot of rep and resolves every host it checked,
+// so DNS answers can be compared between runs
+func Take(ctx context.Context, rep *suite.Report, version string) Snapshot {
+	s := Snapshot{
+		Taken:      rep.Finished,
+		Version:    version,
+		Proxy:      rep.Proxy,
+		EnvProxies: make(map[string]string),
+		DNS:        Resolve(ctx, rep.Results),
+	}
+	// mask like Save does, so a fresh snapshot compares equal to a saved one
+	for _, r := range rep.Results {
+		r.Endpoint = redact.String(r.Endpoint)
+		r.Error = redact.String(r.Error)
+		r.Detail = redact.String(r.Detail)
+		s.Results = append(s.Results, r)
+	}
+	s.Hostname, _ = os.Hostname()
+	for k, v := range proxy.EnvProxies() {
+		s.EnvProxies[k] = redact.String(v)
+	}
+	return s
+}
+
+// Host returns the host name a result connected to
+func Host(r connections.Result) string {
+	if u, err := url.Parse(r.Endpoint); err == nil && u.Host != "" {
+		return u.Hostname()
+	}
+	if h, _, err := net.SplitHostPort(r.Endpoint); err == nil {
+		return h
+	}
+	return ""
+}
+
+// Resolve looks up the addresses of every host in results, sorted so
+// answers from different runs compare equal when nothing changed.  Hosts
+// a proxy resolved or a --resolve or --connect-to rule remapped are
+// skipped, the checks never used the local answer for them.
+func Resolve(ctx context.Context, results []connections.Result) map[string][]string {
+	answers := make(map[string][]string)
+	for _, r := range results {
+		host := Host(r)
+		if host == "" || net.ParseIP(host) != nil || r.RemoteDNS || r.RemappedTo != "" {
+			continue
+		}
+		if _, done := answers[host]; done {
+			continue
+		}
+		lookup, cancel := context.WithTimeout(ctx, 5*time.Second)
+		addrs, err := net.DefaultResolver.LookupHost(lookup, host)
+		cancel()
+		if err != nil {
+			addrs = []string{"error: " + err.Error()}
+		}
+		sort.Strings(addrs)
+		answers[host] = addrs
+	}
+	return answers
+}
+
+// Save writes the snapshot as indented JSON, creating the directory if needed
+func Save(path string, s Snapshot) error {
+	raw, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(redact.String(string(raw))), 0600)
+}
+
+// Load reads a snapshot written by Save
+func Load(path string) (Snapshot, error) {
+	var s Snapshot
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	err = json.Unmarshal(raw, &s)
+	return s, err
+}
@@ -0,0 +1,33 @@
+package suite
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/mdsauce/nethelp/connections"
+	"github.com/mdsauce/nethelp/endpoints"
+	"github.com/mdsauce/nethelp/proxy"
+	"github.com/mdsauce/nethelp/recommend"
+	log "github.com/sirupsen/logrus"
+)
+
+// Recommend measures samples connect and TLS handshakes to the ondemand and
+// API host of every data center over the network of opts, and ranks them.
+// Only the Proxy, Resolve and ConnectTo options apply.
+func Recommend(ctx context.Context, opts Options, samples int) ([]recommend.Score, error) {
+	remapper, err := opts.remapper()
+	if err != nil {
+		return nil, err
+	}
+	proxyURL, err := proxy.Parse(opts.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	if opts.BaseURL != "" {
+		log.Warn("--base-url is ignored, the data centers are measured at saucelabs.com.")
+	}
+	r := connections.NewRunner(ctx, &http.Client{}, opts.Out)
+	r.Proxy = proxyURL
+	r.Remap = remapper
+	return recommend.Rank(ctx, endpoints.Regions(), samples, r.Handshake), nil
+}